@@ -0,0 +1,319 @@
+package config
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// AliasOption define method to modify key alias
+type AliasOption func(a *alias)
+
+// Deprecated mark aliased key as deprecated, its usage
+// is logged once and listed in deprecation report
+func Deprecated(message string) AliasOption {
+	return func(a *alias) {
+		a.deprecated = true
+		a.message = message
+	}
+}
+
+// Alias resolve path from (and its children) to path to, both in lookups
+// and in loaded sources, so config keys can be renamed without breaking
+// services still using the old key
+func Alias(from, to string, opts ...AliasOption) Option {
+	a := &alias{from: from, to: to}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return func(o *Options) {
+		o.aliases = append(o.aliases, a)
+	}
+}
+
+// Deprecation report usage of deprecated key
+type Deprecation struct {
+	Key         string
+	Replacement string
+	Message     string
+
+	// Source where the key is first used, either lookup, query,
+	// persist or merged config
+	Source string
+
+	// Time when the key is first used
+	Time time.Time
+}
+
+type alias struct {
+	from       string
+	to         string
+	deprecated bool
+	message    string
+
+	// resolved and normalized paths
+	fromSegs []pathSegment
+	toSegs   []pathSegment
+}
+
+// aliases resolve aliased path and track deprecated key usage
+type aliases struct {
+	list []*alias
+
+	sync.Mutex
+	deprecations []Deprecation
+	reported     map[string]bool
+}
+
+// newAliases resolve paths of aliases with given key normalizer
+func newAliases(list []*alias, normalize KeyNormalizer) *aliases {
+	res := &aliases{reported: make(map[string]bool)}
+
+	for _, a := range list {
+		from, err := parsePath(normalizePath([]string{a.from}, normalize)[0])
+		if err != nil || len(from) == 0 {
+			log.Println("error resolve alias path, from:", a.from, "err:", err)
+			continue
+		}
+
+		to, err := parsePath(normalizePath([]string{a.to}, normalize)[0])
+		if err != nil || len(to) == 0 {
+			log.Println("error resolve alias path, to:", a.to, "err:", err)
+			continue
+		}
+
+		a.fromSegs = from
+		a.toSegs = to
+		res.list = append(res.list, a)
+	}
+
+	return res
+}
+
+// rewrite replace aliased prefix of path, return nil alias when not aliased
+func (as *aliases) rewrite(segs []pathSegment) ([]pathSegment, *alias) {
+	for _, a := range as.list {
+		if !hasPathPrefix(segs, a.fromSegs) {
+			continue
+		}
+
+		res := make([]pathSegment, 0, len(a.toSegs)+len(segs)-len(a.fromSegs))
+		res = append(res, a.toSegs...)
+		res = append(res, segs[len(a.fromSegs):]...)
+
+		return res, a
+	}
+
+	return segs, nil
+}
+
+// migrate move values of aliased paths in merged snapshot to their new path,
+// values already set in new path take precedence
+func (as *aliases) migrate(snap *Snapshot) (*Snapshot, error) {
+	if len(as.list) == 0 || snap == nil || len(snap.Data) == 0 {
+		return snap, nil
+	}
+
+	if snap.tree != nil {
+		return as.migrateTree(snap)
+	}
+
+	tree := snap.parsed
+	if tree == nil {
+		if err := decodeJSON(snap.Data, &tree); err != nil {
+			return nil, err
+		}
+	} else if as.aliased(tree) {
+		// parsed tree of snapshot is kept intact
+		tree = copyTree(tree)
+	}
+
+	var moved bool
+	for _, a := range as.list {
+		v, ok := getSegments(tree, a.fromSegs)
+		if !ok {
+			continue
+		}
+
+		tree = delSegments(tree, a.fromSegs)
+		cur, _ := getSegments(tree, a.toSegs)
+		tree = setSegments(tree, a.toSegs, mergeMissing(cur, v))
+
+		moved = true
+		as.report(a, "merged config")
+	}
+
+	if !moved {
+		return snap, nil
+	}
+
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Data:       b,
+		Metadata:   snap.Metadata,
+		Components: snap.Components,
+		parsed:     tree,
+	}, nil
+}
+
+// migrateTree move values of aliased paths in merged tree, moved nodes
+// keep metadata of their source
+func (as *aliases) migrateTree(snap *Snapshot) (*Snapshot, error) {
+	root := snap.tree
+
+	var moved bool
+	for _, a := range as.list {
+		v, ok := root.get(a.fromSegs)
+		if !ok {
+			continue
+		}
+
+		root = root.del(a.fromSegs)
+		cur, _ := root.get(a.toSegs)
+		root = root.set(a.toSegs, mergeMissingNode(cur, v))
+
+		moved = true
+		as.report(a, "merged config")
+	}
+
+	if !moved {
+		return snap, nil
+	}
+
+	b, err := root.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Data:       b,
+		Metadata:   snap.Metadata,
+		Components: snap.Components,
+		tree:       root,
+	}, nil
+}
+
+// aliased return whether tree contains any aliased path
+func (as *aliases) aliased(tree interface{}) bool {
+	for _, a := range as.list {
+		if _, ok := getSegments(tree, a.fromSegs); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// report log deprecated alias usage once
+func (as *aliases) report(a *alias, source string) {
+	if !a.deprecated {
+		return
+	}
+
+	as.Lock()
+	defer as.Unlock()
+
+	if as.reported[a.from] {
+		return
+	}
+
+	as.reported[a.from] = true
+	as.deprecations = append(as.deprecations, Deprecation{
+		Key:         a.from,
+		Replacement: a.to,
+		Message:     a.message,
+		Source:      source,
+		Time:        time.Now(),
+	})
+
+	log.Printf("config key %s is deprecated, use %s instead: %s", a.from, a.to, a.message)
+}
+
+// deprecated return copy of deprecation report
+func (as *aliases) deprecated() []Deprecation {
+	as.Lock()
+	defer as.Unlock()
+
+	res := make([]Deprecation, len(as.deprecations))
+	copy(res, as.deprecations)
+
+	return res
+}
+
+// mergeMissing fill keys missing from dst objects with values of src,
+// existing values of dst take precedence
+func mergeMissing(dst, src interface{}) interface{} {
+	if dst == nil {
+		return src
+	}
+
+	dm, ok := dst.(map[string]interface{})
+	if !ok {
+		return dst
+	}
+
+	sm, ok := src.(map[string]interface{})
+	if !ok {
+		return dst
+	}
+
+	for k, v := range sm {
+		dm[k] = mergeMissing(dm[k], v)
+	}
+
+	return dm
+}
+
+// mergeMissingNode is mergeMissing of tree nodes, unchanged nodes are shared
+func mergeMissingNode(dst, src *node) *node {
+	if dst == nil || (dst.kind == scalarNode && dst.val == nil) {
+		return src
+	}
+
+	if dst.kind != objectNode || src.kind != objectNode {
+		return dst
+	}
+
+	n := &node{
+		kind:   objectNode,
+		keys:   make([]string, len(dst.keys), len(dst.keys)+len(src.keys)),
+		fields: make(map[string]*node, len(dst.fields)+len(src.fields)),
+		meta:   dst.meta,
+	}
+
+	copy(n.keys, dst.keys)
+	for k, child := range dst.fields {
+		n.fields[k] = child
+	}
+
+	for _, k := range src.keys {
+		prev, ok := n.fields[k]
+		if !ok {
+			n.keys = append(n.keys, k)
+		}
+
+		n.fields[k] = mergeMissingNode(prev, src.fields[k])
+	}
+
+	return n
+}
+
+func hasPathPrefix(segs, prefix []pathSegment) bool {
+	if len(segs) < len(prefix) {
+		return false
+	}
+
+	for i, seg := range prefix {
+		if seg != segs[i] {
+			return false
+		}
+	}
+
+	return true
+}
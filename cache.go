@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+)
+
+// Codec encode stream before persisted and decode it back once loaded,
+// it is counterpart of Decoder which can be used to encrypt cached config
+type Codec interface {
+	Decoder
+
+	Encode([]byte) []byte
+}
+
+// cacheEntry is persisted last known good merged snapshot
+type cacheEntry struct {
+	Metadata   Metadata        `json:"metadata"`
+	Components []Metadata      `json:"components,omitempty"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// fileCache persist last known good merged snapshot to local file
+type fileCache struct {
+	file  string
+	codec Codec
+}
+
+// save write snapshot to cache file atomically
+func (f *fileCache) save(snap *Snapshot) error {
+	b, err := json.Marshal(&cacheEntry{
+		Metadata:   snap.Metadata,
+		Components: snap.Components,
+		Data:       snap.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	if f.codec != nil {
+		b = f.codec.Encode(b)
+	}
+
+	return writeFileAtomic(f.file, b, 0600)
+}
+
+// load read snapshot from cache file
+func (f *fileCache) load() (*Snapshot, error) {
+	b, err := ioutil.ReadFile(f.file)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.codec != nil {
+		b = f.codec.Decode(b)
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		Data:       entry.Data,
+		Metadata:   entry.Metadata,
+		Components: entry.Components,
+	}
+
+	// keep original metadata, mark where it is loaded from
+	snap.Source = "cache:" + f.file
+	snap.Timestamp = time.Now()
+
+	return snap, nil
+}
@@ -69,6 +69,17 @@ func decode(src []byte) []byte {
 	return plainText
 }
 
+type appConfig struct {
+	Name   string `json:"name" usage:"person name"`
+	Slogan string `json:"slogan" usage:"person slogan"`
+	Nested struct {
+		Props struct {
+			Deep   string `json:"deep" usage:"cli nested"`
+			Really int    `json:"really" usage:"cli nested"`
+		} `json:"props"`
+	} `json:"nested"`
+}
+
 type decoder struct{}
 
 func (d *decoder) Decode(src []byte) []byte {
@@ -76,13 +87,12 @@ func (d *decoder) Decode(src []byte) []byte {
 }
 
 func main() {
-	var name, slogan, nested1 string
-	var nested2 int
+	cfg := &appConfig{}
+	cfg.Nested.Props.Really = 55
 
-	flag.StringVar(&name, "name", "", "person name")
-	flag.StringVar(&slogan, "slogan", "", "person slogan")
-	flag.StringVar(&nested1, "nested.props.deep", "", "cli nested")
-	flag.IntVar(&nested2, "nested.props.really", 55, "cli nested")
+	if err := config.RegisterFlags(flag.CommandLine, cfg); err != nil {
+		log.Fatal(err)
+	}
 
 	flag.Parse()
 
@@ -92,6 +102,14 @@ func main() {
 		log.Fatal(err)
 	}
 
+	etcdSource, err := config.Etcd("127.0.0.1:2379", config.EtcdOption{
+		Prefix:      "/configuration/app",
+		DialTimeout: time.Second * 2,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// <- s.Notify()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -106,12 +124,7 @@ func main() {
 		config.WithSource(
 			config.Cli(),
 		),
-		config.WithSource(
-			config.Etcd("127.0.0.1:2379", config.EtcdOption{
-				Prefix:      "/configuration/app",
-				DialTimeout: time.Second * 2,
-			}),
-		),
+		config.WithSource(etcdSource),
 		config.EnableWatcher(ctx, time.Second*5),
 	)
 
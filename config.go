@@ -1,9 +1,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,6 +14,59 @@ type Config interface {
 	Values
 
 	Subscribe() <-chan struct{}
+
+	// Snapshot return current merged snapshot along with its metadata
+	Snapshot() *Snapshot
+
+	// History return kept merged snapshots, oldest first
+	History() []Revision
+
+	// Rollback pin config to merged snapshot of given version
+	// until next change of sources or pin is released
+	Rollback(version uint64) error
+
+	// Release unpin rolled back snapshot and restore latest one
+	Release() error
+
+	// Persist write value of given path to writable source and reload config,
+	// nil value delete the path from source
+	Persist(path string, val interface{}, source Loader) error
+
+	// Override set top priority value of given path which survive source reloads
+	Override(path string, val interface{}, opts ...OverrideOption)
+
+	// RemoveOverride remove runtime override of given path
+	RemoveOverride(path string)
+
+	// Sub return view of config rooted at given path,
+	// following changes of the parent config
+	Sub(path string) Config
+
+	// Query return values selected by JSONPath-style expression,
+	// e.g. $.upstreams[?(@.region=='eu')].host
+	Query(expr string) ([]Value, error)
+
+	// Keys return sorted keys of object at given path
+	Keys(path ...string) []string
+
+	// Walk call fn for every leaf value in key order, array indexes
+	// appear in path as [n], walking stop on first error returned by fn
+	Walk(fn func(path []string, v Value) error) error
+
+	// Flatten return leaf values by their path expression, e.g. servers[0].host
+	Flatten() map[string]Value
+
+	// Export encode current values in given format: json, yaml, toml or env,
+	// e.g. Export("yaml", RedactKeys("password"))
+	Export(format string, opts ...ExportOption) ([]byte, error)
+
+	// Origin return metadata of source providing value of path,
+	// available when values are read by TreeReader
+	Origin(path string) (Metadata, bool)
+
+	// Deprecations return deprecated aliased keys found in sources
+	// or used by lookups, in order of first usage
+	Deprecations() []Deprecation
 }
 
 type config struct {
@@ -19,43 +74,88 @@ type config struct {
 
 	options Options
 
+	// serialize read and merge of sources
+	reload sync.Mutex
+
 	// latest snapshots
 	snaps []*Snapshot
 
-	// current merged snapshot
-	snap *Snapshot
+	// parsed latest snapshots, ready to merge
+	parsed []*Snapshot
 
-	// loaded snapshot
-	values Values
+	// current state, read lock-free and replaced on every change
+	state atomic.Value
+
+	// previous merged snapshots
+	history *history
+
+	// rolled back revision, nil when not pinned
+	pinned *Revision
+
+	// runtime overrides by path
+	overrides map[string]*override
+
+	// key aliases
+	aliases *aliases
 
 	// subscriber of config changes
-	subscribers []chan struct{}
+	subscribers []*subscriber
+}
+
+// subscriber of config changes, filtered subscriber is notified
+// only when checksum of its view of values changed
+type subscriber struct {
+	ch chan struct{}
+
+	// sum return checksum of watched values, nil for all values
+	sum  func() string
+	last string
+}
+
+// state is immutable view of config values along with merged snapshot
+// it is loaded from, changes always publish new state
+type state struct {
+	snap *Snapshot
+
+	// values without runtime overrides
+	base Values
+
+	// values with runtime overrides applied
+	values Values
 }
 
 // New initialize configuration with customizable options
 // merge ordering start from first argument and last argument as final source
 func New(opts ...Option) Config {
 	init := Options{
-		sources: make([]Loader, 0),
-		reader:  &jsonReader{},
-		merger:  &jsonMerger{},
+		sources:     make([]Loader, 0),
+		reader:      &jsonReader{},
+		merger:      &jsonMerger{},
+		historySize: 10,
 	}
 
 	// merge options
 	options := mergeOptions(init, opts...)
 
 	c := &config{
-		options: options,
+		options:   options,
+		history:   &history{size: options.historySize},
+		overrides: make(map[string]*override),
+		aliases:   newAliases(options.aliases, options.normalizer),
 	}
 
-	// read initial values
+	// read initial values, fallback to last known good cache
 	if err := c.readAndMergeConfigs(); err != nil {
-		log.Fatal("error read initial value: ", err)
+		if cerr := c.readCache(); cerr != nil {
+			log.Fatal("error read initial value: ", err)
+		}
+
+		log.Println("error read initial value, loaded from cache, err:", err)
 	}
 
 	if options.watch {
 		// create subscribers
-		c.subscribers = make([]chan struct{}, 0)
+		c.subscribers = make([]*subscriber, 0)
 
 		fmt.Println("watch changes...")
 		go c.watchChanges()
@@ -65,12 +165,20 @@ func New(opts ...Option) Config {
 }
 
 func (c *config) watchChanges() {
+	// notified changes trigger immediate read
+	trigger := make(chan struct{}, 1)
+
 	// run watchers
 	for _, source := range c.options.sources {
 		watcher, ok := source.(Watchable)
 		if ok {
 			go watcher.Watch(c.options.ctx)
 		}
+
+		notifier, ok := source.(Notifier)
+		if ok {
+			go c.forwardNotify(notifier.Notify(), trigger)
+		}
 	}
 
 	// periodically get snapshot of config sources
@@ -80,11 +188,15 @@ func (c *config) watchChanges() {
 			// close subscriber
 			c.RLock()
 			for _, subscriber := range c.subscribers {
-				close(subscriber)
+				close(subscriber.ch)
 			}
 			c.RUnlock()
 
 			return
+		case <-trigger:
+			if err := c.readAndMergeConfigs(); err != nil {
+				log.Println("error update and merge config, err:", err)
+			}
 		case <-time.After(c.options.watchDuration):
 			if err := c.readAndMergeConfigs(); err != nil {
 				log.Println("error update and merge config, err:", err)
@@ -95,7 +207,30 @@ func (c *config) watchChanges() {
 	}
 }
 
+// forwardNotify pass source notification to trigger until context done
+func (c *config) forwardNotify(notify <-chan struct{}, trigger chan<- struct{}) {
+	for {
+		select {
+		case <-c.options.ctx.Done():
+			return
+		case _, ok := <-notify:
+			if !ok {
+				return
+			}
+
+			select {
+			case trigger <- struct{}{}:
+			default:
+				// pending trigger
+			}
+		}
+	}
+}
+
 func (c *config) readAndMergeConfigs() error {
+	c.reload.Lock()
+	defer c.reload.Unlock()
+
 	// collect all config snapshots
 	snaps := make([]*Snapshot, len(c.options.sources))
 
@@ -122,8 +257,30 @@ func (c *config) readAndMergeConfigs() error {
 	}
 
 	if changed {
+		// parse changed sources only, unchanged sources reuse parsed snapshot
+		merging := make([]*Snapshot, len(snaps))
+		for i, snap := range snaps {
+			if len(c.parsed) == len(snaps) && c.snaps[i].Checksum() == snap.Checksum() {
+				merging[i] = c.parsed[i]
+				continue
+			}
+
+			p, err := c.parse(snap)
+			if err != nil {
+				return err
+			}
+
+			merging[i] = p
+		}
+
 		// merge snapshot
-		snap, err := c.options.merger.Merge(snaps...)
+		snap, err := c.options.merger.Merge(merging...)
+		if err != nil {
+			return err
+		}
+
+		// move values of aliased keys to their new path
+		snap, err = c.aliases.migrate(snap)
 		if err != nil {
 			return err
 		}
@@ -134,77 +291,580 @@ func (c *config) readAndMergeConfigs() error {
 			return err
 		}
 
-		// update current value
+		// update current value, new change release rolled back snapshot
 		c.Lock()
+		c.history.add(snap)
 		c.snaps = snaps
-		c.snap = snap
-		c.values = values
+		c.parsed = merging
+		c.store(snap, values)
+		c.pinned = nil
 		c.Unlock()
 
-		// notify all subsribers
-		c.RLock()
-		for _, subscriber := range c.subscribers {
-			select {
-			case subscriber <- struct{}{}:
-			default:
-				// overflow
+		c.notify()
+
+		if c.options.cache != nil {
+			if err := c.options.cache.save(snap); err != nil {
+				log.Println("error save config cache, err:", err)
 			}
 		}
-		c.RUnlock()
 	}
 
 	return nil
 }
 
-func (c *config) Subscribe() <-chan struct{} {
+// parse return source snapshot ready to merge, with representation used
+// by merger and normalized keys
+func (c *config) parse(snap *Snapshot) (*Snapshot, error) {
+	if p, ok := c.options.merger.(snapshotParser); ok {
+		n, err := p.parse(snap)
+		if err != nil {
+			return nil, err
+		}
+
+		snap = n
+	}
+
+	// parsed representation is normalized, so keys keep order of the tree
+	if c.options.normalizer != nil {
+		return normalizeSnapshot(snap, c.options.normalizer)
+	}
+
+	return snap, nil
+}
+
+// readCache load last known good snapshot from cache
+func (c *config) readCache() error {
+	if c.options.cache == nil {
+		return errors.New("cache is not configured")
+	}
+
+	snap, err := c.options.cache.load()
+	if err != nil {
+		return err
+	}
+
+	values, err := c.options.reader.Read(snap)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	c.history.add(snap)
+	c.store(snap, values)
+	c.Unlock()
+
+	return nil
+}
+
+// load return current state
+func (c *config) load() *state {
+	return c.state.Load().(*state)
+}
+
+// store publish new state with runtime overrides applied on top of base values,
+// caller must hold write lock
+func (c *config) store(snap *Snapshot, base Values) {
+	values := base
+
+	if len(c.overrides) > 0 {
+		v, err := c.copyValues(snap, base)
+		if err != nil {
+			log.Println("error apply config overrides, err:", err)
+		} else {
+			applyOverrides(v, c.overrides)
+			values = v
+		}
+	}
+
+	c.state.Store(&state{snap: snap, base: base, values: values})
+}
+
+// cloner is implemented by values which are never modified in place,
+// copy share data with original values
+type cloner interface {
+	clone() Values
+}
+
+// copyValues return copy of values which can be modified, values of
+// custom reader are copied by reading their bytes again
+func (c *config) copyValues(snap *Snapshot, values Values) (Values, error) {
+	if v, ok := values.(cloner); ok {
+		return v.clone(), nil
+	}
+
+	return c.options.reader.Read(&Snapshot{
+		Data:     values.Bytes(),
+		Metadata: snap.Metadata,
+	})
+}
+
+// modify publish copy of current base values changed by fn, published values
+// are never mutated in place. Built-in values copy only objects along modified
+// path and share the rest, values of custom reader are fully copied
+func (c *config) modify(fn func(Values)) {
 	c.Lock()
 	defer c.Unlock()
 
-	// buffered channel to prevent blocking
-	s := make(chan struct{}, 1)
-	c.subscribers = append(c.subscribers, s)
+	cur := c.load()
 
-	return s
+	values, err := c.copyValues(cur.snap, cur.base)
+	if err != nil {
+		log.Println("error copy config values, err:", err)
+		return
+	}
+
+	fn(values)
+
+	c.store(cur.snap, values)
 }
 
-func (c *config) Bytes() []byte {
+func (c *config) Override(path string, val interface{}, opts ...OverrideOption) {
+	path = c.normalizeString(path)
+
+	var options overrideOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	o := &override{val: val}
+
+	c.Lock()
+	if prev, ok := c.overrides[path]; ok {
+		prev.stop()
+	}
+
+	if options.ttl > 0 {
+		o.timer = time.AfterFunc(options.ttl, func() {
+			c.expireOverride(path, o)
+		})
+	}
+
+	c.overrides[path] = o
+
+	cur := c.load()
+	c.store(cur.snap, cur.base)
+	c.Unlock()
+
+	c.notify()
+}
+
+func (c *config) RemoveOverride(path string) {
+	path = c.normalizeString(path)
+
+	c.Lock()
+	o, ok := c.overrides[path]
+	if !ok {
+		c.Unlock()
+		return
+	}
+
+	o.stop()
+	delete(c.overrides, path)
+
+	cur := c.load()
+	c.store(cur.snap, cur.base)
+	c.Unlock()
+
+	c.notify()
+}
+
+// expireOverride remove override once its ttl passed,
+// unless it is already replaced
+func (c *config) expireOverride(path string, o *override) {
+	c.Lock()
+	if c.overrides[path] != o {
+		c.Unlock()
+		return
+	}
+
+	delete(c.overrides, path)
+
+	cur := c.load()
+	c.store(cur.snap, cur.base)
+	c.Unlock()
+
+	c.notify()
+}
+
+// notify all subscribers
+func (c *config) notify() {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, subscriber := range c.subscribers {
+		if subscriber.sum != nil {
+			sum := subscriber.sum()
+			if sum == subscriber.last {
+				continue
+			}
+
+			subscriber.last = sum
+		}
+
+		select {
+		case subscriber.ch <- struct{}{}:
+		default:
+			// overflow
+		}
+	}
+}
+
+func (c *config) History() []Revision {
 	c.RLock()
 	defer c.RUnlock()
 
-	return c.values.Bytes()
+	return c.history.list()
 }
 
-func (c *config) Get(path ...string) Value {
+func (c *config) Rollback(version uint64) error {
 	c.RLock()
-	defer c.RUnlock()
+	rev := c.history.get(version)
+	c.RUnlock()
+
+	if rev == nil {
+		return fmt.Errorf("revision not found: %d", version)
+	}
+
+	values, err := c.options.reader.Read(rev.Snapshot)
+	if err != nil {
+		return err
+	}
 
-	return c.values.Get(path...)
+	c.Lock()
+	c.store(rev.Snapshot, values)
+	c.pinned = rev
+	c.Unlock()
+
+	c.notify()
+
+	return nil
 }
 
-func (c *config) Set(val interface{}, path ...string) {
+func (c *config) Release() error {
 	c.RLock()
-	defer c.RUnlock()
+	pinned := c.pinned
+	latest := c.history.latest()
+	c.RUnlock()
+
+	if pinned == nil || latest == nil {
+		return nil
+	}
+
+	values, err := c.options.reader.Read(latest.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	// newer change may already release the pin
+	if c.pinned == pinned {
+		c.store(latest.Snapshot, values)
+		c.pinned = nil
+	}
+	c.Unlock()
+
+	c.notify()
+
+	return nil
+}
+
+func (c *config) Subscribe() <-chan struct{} {
+	return c.subscribe(nil)
+}
+
+// subscribe register subscriber notified when checksum returned
+// by sum changed, or on every change when sum is nil
+func (c *config) subscribe(sum func() string) <-chan struct{} {
+	c.Lock()
+	defer c.Unlock()
+
+	// buffered channel to prevent blocking
+	s := &subscriber{ch: make(chan struct{}, 1), sum: sum}
+	if sum != nil {
+		s.last = sum()
+	}
+
+	c.subscribers = append(c.subscribers, s)
+
+	return s.ch
+}
+
+func (c *config) Snapshot() *Snapshot {
+	return c.load().snap
+}
+
+func (c *config) Persist(path string, val interface{}, source Loader) error {
+	w, ok := source.(Writable)
+	if !ok {
+		return errors.New("source is not writable")
+	}
+
+	// keys are normalized as lookups are, source matches them
+	// against its existing keys when it's able to
+	segs, err := resolvePath(normalizePath([]string{path}, c.options.normalizer))
+	if err != nil {
+		return err
+	}
+
+	if len(segs) == 0 {
+		return errors.New("path is required")
+	}
+
+	// aliased path is written to its new path
+	if res, a := c.aliases.rewrite(segs); a != nil {
+		c.aliases.report(a, "persist")
+		segs = res
+	}
+
+	keys, err := pathKeys(segs)
+	if err != nil {
+		return err
+	}
+
+	nw, ok := w.(normalizedWritable)
+
+	switch {
+	case ok && val == nil:
+		err = nw.deleteNormalized(keys, c.options.normalizer)
+	case ok:
+		err = nw.writeNormalized(keys, val, c.options.normalizer)
+	case val == nil:
+		err = w.Delete(keys)
+	default:
+		err = w.Write(keys, val)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.readAndMergeConfigs()
+}
+
+func (c *config) Sub(path string) Config {
+	if path == "" {
+		return c
+	}
+
+	return &subConfig{parent: c, prefix: c.normalizeString(path)}
+}
+
+func (c *config) Query(expr string) ([]Value, error) {
+	return c.query("", expr)
+}
+
+// tree return copy of decoded subtree of given path
+func (c *config) tree(path string) (interface{}, bool, error) {
+	segs, err := resolvePath([]string{c.normalizeString(path)})
+	if err != nil {
+		return nil, false, err
+	}
+
+	tree, ok := getSegments(c.load().values.Map(), segs)
+
+	return tree, ok, nil
+}
+
+// query evaluate expression over subtree of given path
+func (c *config) query(path, expr string) ([]Value, error) {
+	tree, ok, err := c.tree(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return []Value{}, nil
+	}
+
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	// keys are normalized and aliases resolved as lookups are
+	normalizeQuery(steps, c.options.normalizer)
+	steps = c.aliasQuery(path, steps)
+
+	matches := evalQuery(tree, steps)
+
+	res := make([]Value, len(matches))
+	for i, m := range matches {
+		res[i] = &jsonValue{Json: newJSON(m.val), path: formatPath(m.path), exists: true}
+	}
+
+	return res, nil
+}
 
-	c.values.Set(val, path...)
+// aliasQuery rewrite aliased key steps leading query over subtree of given path
+func (c *config) aliasQuery(path string, steps []queryStep) []queryStep {
+	if len(c.aliases.list) == 0 {
+		return steps
+	}
+
+	prefix, err := resolvePath([]string{c.normalizeString(path)})
+	if err != nil {
+		return steps
+	}
+
+	n := 0
+	segs := append([]pathSegment{}, prefix...)
+	for ; n < len(steps) && steps[n].seg != nil && !steps[n].recursive; n++ {
+		segs = append(segs, *steps[n].seg)
+	}
+
+	segs, a := c.aliases.rewrite(segs)
+	if a == nil || !hasPathPrefix(segs, prefix) {
+		return steps
+	}
+
+	c.aliases.report(a, "query")
+
+	res := make([]queryStep, 0, len(segs)-len(prefix)+len(steps)-n)
+	for i := len(prefix); i < len(segs); i++ {
+		res = append(res, queryStep{seg: &segs[i]})
+	}
+
+	return append(res, steps[n:]...)
+}
+
+func (c *config) Keys(path ...string) []string {
+	return c.keys(joinPath("", path))
+}
+
+// keys return sorted keys of object at given path
+func (c *config) keys(path string) []string {
+	tree, _, _ := c.tree(path)
+
+	m, ok := tree.(map[string]interface{})
+	if !ok {
+		return []string{}
+	}
+
+	return sortedKeys(m)
+}
+
+func (c *config) Walk(fn func(path []string, v Value) error) error {
+	return c.walk("", fn)
+}
+
+// walk call fn for every leaf of subtree at given path
+func (c *config) walk(path string, fn func(path []string, v Value) error) error {
+	tree, ok, err := c.tree(path)
+	if err != nil || !ok {
+		return err
+	}
+
+	return walkTree(tree, []string{}, []pathSegment{}, fn)
+}
+
+func (c *config) Flatten() map[string]Value {
+	return c.flatten("")
+}
+
+// flatten return leaves of subtree at given path by their path expression
+func (c *config) flatten(path string) map[string]Value {
+	res := make(map[string]Value)
+
+	c.walk(path, func(_ []string, v Value) error {
+		res[v.(*jsonValue).path] = v
+		return nil
+	})
+
+	return res
+}
+
+func (c *config) Export(format string, opts ...ExportOption) ([]byte, error) {
+	return c.export("", format, opts...)
+}
+
+// export encode subtree at given path in given format
+func (c *config) export(path, format string, opts ...ExportOption) ([]byte, error) {
+	tree, _, err := c.tree(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return export(tree, format, append(opts, exportNormalizer(c.options.normalizer))...)
+}
+
+func (c *config) Bytes() []byte {
+	return c.load().values.Bytes()
+}
+
+func (c *config) Get(path ...string) Value {
+	return c.load().values.Get(c.normalize(path)...)
+}
+
+func (c *config) Set(val interface{}, path ...string) {
+	path = c.normalize(path)
+
+	c.modify(func(values Values) {
+		values.Set(val, path...)
+	})
 }
 
 func (c *config) Del(path ...string) {
-	c.RLock()
-	defer c.RUnlock()
+	path = c.normalize(path)
 
-	c.values.Del(path...)
+	c.modify(func(values Values) {
+		values.Del(path...)
+	})
 }
 
-func (c *config) Map() map[string]interface{} {
-	c.RLock()
-	defer c.RUnlock()
+// normalize return path with normalized keys and aliases resolved
+func (c *config) normalize(path []string) []string {
+	if len(c.aliases.list) == 0 {
+		return normalizePath(path, c.options.normalizer)
+	}
+
+	path = normalizePath(path, c.options.normalizer)
+
+	segs, err := resolvePath(path)
+	if err != nil {
+		return path
+	}
+
+	segs, a := c.aliases.rewrite(segs)
+	if a == nil {
+		return path
+	}
 
-	return c.values.Map()
+	c.aliases.report(a, "lookup")
+
+	return []string{formatPath(segs)}
 }
 
-func (c *config) Scan(v interface{}) error {
-	c.RLock()
-	defer c.RUnlock()
+// normalizeString return path expression with normalized keys and aliases resolved
+func (c *config) normalizeString(path string) string {
+	if c.options.normalizer == nil && len(c.aliases.list) == 0 {
+		return path
+	}
 
-	return c.values.Scan(v)
+	return c.normalize([]string{path})[0]
+}
+
+func (c *config) Origin(path string) (Metadata, bool) {
+	v, ok := c.load().values.(*treeValues)
+	if !ok {
+		return Metadata{}, false
+	}
+
+	segs, err := resolvePath([]string{c.normalizeString(path)})
+	if err != nil {
+		return Metadata{}, false
+	}
+
+	return v.origin(segs)
+}
+
+func (c *config) Deprecations() []Deprecation {
+	return c.aliases.deprecated()
+}
+
+func (c *config) Map() map[string]interface{} {
+	return c.load().values.Map()
+}
+
+func (c *config) Scan(v interface{}) error {
+	return c.load().values.Scan(v)
 }
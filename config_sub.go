@@ -0,0 +1,107 @@
+package config
+
+// subConfig is view of config rooted at prefix path
+type subConfig struct {
+	parent *config
+	prefix string
+}
+
+// path return parent path expression of given relative path
+func (s *subConfig) path(path ...string) string {
+	return joinPath(s.prefix, path)
+}
+
+func (s *subConfig) Bytes() []byte {
+	return s.parent.Get(s.prefix).Bytes()
+}
+
+func (s *subConfig) Get(path ...string) Value {
+	return s.parent.Get(s.path(path...))
+}
+
+func (s *subConfig) Set(val interface{}, path ...string) {
+	s.parent.Set(val, s.path(path...))
+}
+
+func (s *subConfig) Del(path ...string) {
+	s.parent.Del(s.path(path...))
+}
+
+func (s *subConfig) Map() map[string]interface{} {
+	return s.parent.Get(s.prefix).Map(map[string]interface{}{})
+}
+
+func (s *subConfig) Scan(v interface{}) error {
+	return s.parent.Get(s.prefix).Scan(v)
+}
+
+// Subscribe notify only when values under prefix changed
+func (s *subConfig) Subscribe() <-chan struct{} {
+	return s.parent.subscribe(func() string {
+		return checksum(s.Bytes())
+	})
+}
+
+func (s *subConfig) Snapshot() *Snapshot {
+	return s.parent.Snapshot()
+}
+
+func (s *subConfig) History() []Revision {
+	return s.parent.History()
+}
+
+func (s *subConfig) Rollback(version uint64) error {
+	return s.parent.Rollback(version)
+}
+
+func (s *subConfig) Release() error {
+	return s.parent.Release()
+}
+
+func (s *subConfig) Persist(path string, val interface{}, source Loader) error {
+	return s.parent.Persist(s.path(path), val, source)
+}
+
+func (s *subConfig) Override(path string, val interface{}, opts ...OverrideOption) {
+	s.parent.Override(s.path(path), val, opts...)
+}
+
+func (s *subConfig) RemoveOverride(path string) {
+	s.parent.RemoveOverride(s.path(path))
+}
+
+func (s *subConfig) Query(expr string) ([]Value, error) {
+	return s.parent.query(s.prefix, expr)
+}
+
+func (s *subConfig) Keys(path ...string) []string {
+	return s.parent.keys(s.path(path...))
+}
+
+func (s *subConfig) Walk(fn func(path []string, v Value) error) error {
+	return s.parent.walk(s.prefix, fn)
+}
+
+func (s *subConfig) Flatten() map[string]Value {
+	return s.parent.flatten(s.prefix)
+}
+
+func (s *subConfig) Export(format string, opts ...ExportOption) ([]byte, error) {
+	return s.parent.export(s.prefix, format, opts...)
+}
+
+func (s *subConfig) Sub(path string) Config {
+	if path == "" {
+		return s
+	}
+
+	return &subConfig{parent: s.parent, prefix: s.path(path)}
+}
+
+func (s *subConfig) Deprecations() []Deprecation {
+	return s.parent.Deprecations()
+}
+
+func (s *subConfig) Origin(path string) (Metadata, bool) {
+	return s.parent.Origin(s.path(path))
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSubConfig() (*config, MemoryLoader) {
+	src := Memory(map[string]interface{}{
+		"app": map[string]interface{}{
+			"db": map[string]interface{}{
+				"host":  "a",
+				"ports": []interface{}{1, 2},
+			},
+			"name": "x",
+		},
+		"other": 1,
+	})
+
+	return New(WithSource(src)).(*config), src
+}
+
+func TestSubRelativePaths(t *testing.T) {
+	c, _ := newSubConfig()
+	sub := c.Sub("app")
+
+	if got := sub.Get("db", "host").String(""); got != "a" {
+		t.Fatalf("got host %q", got)
+	}
+
+	if sub.Get("other").Exists() {
+		t.Fatal("value outside of prefix exists")
+	}
+
+	if got := strings.Join(sub.Keys(), ","); got != "db,name" {
+		t.Fatalf("got keys %s", got)
+	}
+
+	if got := strings.Join(sub.Keys("db"), ","); got != "host,ports" {
+		t.Fatalf("got keys of db %s", got)
+	}
+
+	var paths []string
+	if err := sub.Walk(func(path []string, v Value) error {
+		paths = append(paths, strings.Join(path, "."))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(paths, ","); got != "db.host,db.ports.[0],db.ports.[1],name" {
+		t.Fatalf("got walked paths %s", got)
+	}
+
+	res, err := sub.Query("$.db.ports[*]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, v := range res {
+		got = append(got, fmt.Sprintf("%s=%d", v.(*jsonValue).path, v.Int(0)))
+	}
+
+	if strings.Join(got, ",") != "db.ports[0]=1,db.ports[1]=2" {
+		t.Fatalf("got query result %v", got)
+	}
+
+	sub.Set("b", "db", "host")
+
+	if got := c.Get("app", "db", "host").String(""); got != "b" {
+		t.Fatalf("set through sub: got host %q", got)
+	}
+}
+
+func TestSubNested(t *testing.T) {
+	c, _ := newSubConfig()
+
+	db := c.Sub("app").Sub("db")
+
+	if got := db.Get("host").String(""); got != "a" {
+		t.Fatalf("got host %q", got)
+	}
+
+	if got := db.Get("ports[1]").Int(0); got != 2 {
+		t.Fatalf("got port %d", got)
+	}
+
+	if got := string(db.Bytes()); got != `{"host":"a","ports":[1,2]}` {
+		t.Fatalf("got bytes %s", got)
+	}
+
+	if db.Sub("") != db {
+		t.Fatal("empty sub path is not the same view")
+	}
+
+	flat := db.Flatten()
+	if len(flat) != 3 || flat["ports[0]"] == nil {
+		t.Fatalf("got flatten keys %v", flat)
+	}
+}
+
+func TestSubSubscribeFilterPrefix(t *testing.T) {
+	c, _ := newSubConfig()
+
+	all := c.Subscribe()
+	db := c.Sub("app.db").Subscribe()
+
+	c.Override("other", 2)
+
+	select {
+	case <-all:
+	default:
+		t.Fatal("change is not notified")
+	}
+
+	select {
+	case <-db:
+		t.Fatal("change outside of prefix is notified")
+	default:
+	}
+
+	c.Override("app.db.host", "b")
+
+	select {
+	case <-db:
+	default:
+		t.Fatal("change under prefix is not notified")
+	}
+
+	// same values under prefix again
+	c.Override("other", 3)
+
+	select {
+	case <-db:
+		t.Fatal("unchanged prefix is notified")
+	default:
+	}
+}
+
+func TestSubSubscribeNoGoroutine(t *testing.T) {
+	c, _ := newSubConfig()
+	sub := c.Sub("app")
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		sub.Subscribe()
+	}
+
+	// give leaked goroutines time to start
+	time.Sleep(time.Millisecond * 10)
+
+	if n := runtime.NumGoroutine() - before; n > 10 {
+		t.Fatalf("%d goroutines started by subscribe", n)
+	}
+}
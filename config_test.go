@@ -0,0 +1,249 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentGetSetReload run readers against writers, overrides and
+// source reloads, run with -race to detect values mutated in place
+func TestConcurrentGetSetReload(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"json": nil,
+		"tree": {WithReader(TreeReader()), WithMerger(TreeMerger())},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := Memory(map[string]interface{}{
+				"db":   map[string]interface{}{"host": "a", "port": 1},
+				"list": []interface{}{1, 2, 3},
+			})
+
+			c := New(append([]Option{WithSource(src)}, opts...)...).(*config)
+
+			// published state must never change
+			published := c.load()
+			before := string(published.values.Bytes())
+
+			const n = 200
+
+			var wg sync.WaitGroup
+			run := func(fn func(i int)) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < n; i++ {
+						fn(i)
+					}
+				}()
+			}
+
+			for r := 0; r < 4; r++ {
+				run(func(i int) {
+					c.Get("db.host").String("")
+					c.Get("list[1]").Int(0)
+					c.Map()
+					c.Flatten()
+				})
+			}
+
+			run(func(i int) {
+				c.Set(i, "counter")
+				c.Set(fmt.Sprint("h", i), "db", "host")
+			})
+
+			run(func(i int) {
+				c.Del("list[0]")
+				c.Set(i, "list[5]")
+			})
+
+			run(func(i int) {
+				c.Override("db.port", i)
+				if i%10 == 0 {
+					c.RemoveOverride("db.port")
+				}
+			})
+
+			run(func(i int) {
+				src.Update(map[string]interface{}{
+					"db":      map[string]interface{}{"host": "b", "port": i},
+					"list":    []interface{}{i},
+					"version": i,
+				})
+
+				if err := c.readAndMergeConfigs(); err != nil {
+					t.Error(err)
+				}
+			})
+
+			wg.Wait()
+
+			if after := string(published.values.Bytes()); after != before {
+				t.Fatalf("published values modified\nbefore %s\nafter  %s", before, after)
+			}
+		})
+	}
+}
+
+func TestSetSharesUnmodifiedValues(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"a": map[string]interface{}{"x": 1},
+		"b": map[string]interface{}{"y": 2},
+	}))).(*config)
+
+	prev := c.load().values.(*jsonValues).sj.Interface().(map[string]interface{})
+
+	c.Set(3, "a", "z")
+
+	cur := c.load().values.(*jsonValues).sj.Interface().(map[string]interface{})
+	if _, ok := prev["a"].(map[string]interface{})["z"]; ok {
+		t.Fatal("previous values modified in place")
+	}
+
+	if fmt.Sprintf("%p", prev["b"]) != fmt.Sprintf("%p", cur["b"]) {
+		t.Fatal("unmodified object is copied")
+	}
+}
+
+func TestPersistNormalizedKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	if err := ioutil.WriteFile(file, []byte(`{"Database":{"Host":"a","Port":1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := map[string]Loader{
+		"memory": Memory(map[string]interface{}{
+			"Database": map[string]interface{}{"Host": "a", "Port": 1},
+		}),
+		"file": File(file),
+	}
+
+	for name, src := range sources {
+		t.Run(name, func(t *testing.T) {
+			c := New(WithSource(src), WithKeyNormalizer(LowerCaseKeys))
+
+			if err := c.Persist("database.host", "b", src); err != nil {
+				t.Fatal(err)
+			}
+
+			snap, err := src.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := `{"Database":{"Host":"b","Port":1}}`
+			if got := compactJSON(t, snap.Data); got != want {
+				t.Fatalf("write: got %s, want %s", got, want)
+			}
+
+			if got := c.Get("database", "host").String(""); got != "b" {
+				t.Fatalf("got host %q", got)
+			}
+
+			if err := c.Persist("DATABASE.host", nil, src); err != nil {
+				t.Fatal(err)
+			}
+
+			if snap, err = src.Load(); err != nil {
+				t.Fatal(err)
+			}
+
+			want = `{"Database":{"Port":1}}`
+			if got := compactJSON(t, snap.Data); got != want {
+				t.Fatalf("delete: got %s, want %s", got, want)
+			}
+
+			if c.Get("database", "host").Exists() {
+				t.Fatal("deleted key exists")
+			}
+		})
+	}
+}
+
+func TestPersistAliasedPath(t *testing.T) {
+	src := Memory(map[string]interface{}{
+		"Database": map[string]interface{}{"Host": "a"},
+	})
+
+	c := New(WithSource(src), WithKeyNormalizer(LowerCaseKeys), Alias("db", "database"))
+
+	if err := c.Persist("DB.host", "b", src); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := src.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"Database":{"Host":"b"}}`
+	if got := compactJSON(t, snap.Data); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func compactJSON(t *testing.T, b []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestHistoryChangesOfParsedSnapshot(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"json": nil,
+		"tree": {WithReader(TreeReader()), WithMerger(TreeMerger())},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := Memory(map[string]interface{}{"a": 1, "l": []interface{}{1}, "b": "x"})
+			c := New(append([]Option{WithSource(src), WithHistory(4)}, opts...)...).(*config)
+
+			src.Update(map[string]interface{}{"a": 2, "l": []interface{}{1, 2}, "n": true})
+			if err := c.readAndMergeConfigs(); err != nil {
+				t.Fatal(err)
+			}
+
+			hist := c.History()
+			if len(hist) != 2 {
+				t.Fatalf("got %d revisions", len(hist))
+			}
+
+			got := fmt.Sprint(hist[1].Changes)
+			want := "[{a 1 2} {b x <nil>} {l [1] [1 2]} {n <nil> true}]"
+			if got != want {
+				t.Fatalf("got %s, want %s", got, want)
+			}
+
+			// changes don't share arrays with snapshot
+			hist[1].Changes[2].New.([]interface{})[0] = 9
+			if got := c.Get("l[0]").Int(0); got != 1 {
+				t.Fatalf("change modified snapshot, got %d", got)
+			}
+
+			if err := c.Rollback(hist[1].Version); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := c.Get("l[0]").Int(0); got != 1 {
+				t.Fatalf("change modified snapshot, got %d", got)
+			}
+		})
+	}
+}
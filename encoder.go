@@ -1,6 +1,290 @@
 package config
 
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Encoder define method to encode and decode config tree
 type Encoder interface {
 	Encode(interface{}) ([]byte, error)
 	Decode([]byte, interface{}) error
 }
+
+var (
+	encodersMu sync.RWMutex
+
+	// built-in encoders by format
+	encoders = map[string]Encoder{
+		"json": &jsonEncoder{},
+		"yaml": &yamlEncoder{},
+		"yml":  &yamlEncoder{},
+		"toml": &tomlEncoder{},
+		"env":  &envEncoder{},
+	}
+)
+
+// RegisterEncoder register encoder of given format used by Export,
+// registering existing format replace the encoder
+func RegisterEncoder(format string, enc Encoder) {
+	encodersMu.Lock()
+	encoders[strings.ToLower(format)] = enc
+	encodersMu.Unlock()
+}
+
+// encoder return registered encoder of given format
+func encoder(format string) (Encoder, bool) {
+	encodersMu.RLock()
+	enc, ok := encoders[strings.ToLower(format)]
+	encodersMu.RUnlock()
+
+	return enc, ok
+}
+
+// jsonEncoder encode indented json
+type jsonEncoder struct{}
+
+func (e *jsonEncoder) Encode(v interface{}) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+func (e *jsonEncoder) Decode(b []byte, v interface{}) error {
+	return decodeJSON(b, v)
+}
+
+type yamlEncoder struct{}
+
+func (e *yamlEncoder) Encode(v interface{}) ([]byte, error) {
+	return yaml.Marshal(plainTree(v))
+}
+
+func (e *yamlEncoder) Decode(b []byte, v interface{}) error {
+	return yaml.Unmarshal(b, v)
+}
+
+type tomlEncoder struct{}
+
+func (e *tomlEncoder) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// toml has no null, nil values are omitted
+	if err := toml.NewEncoder(&buf).Encode(omitNil(plainTree(v))); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (e *tomlEncoder) Decode(b []byte, v interface{}) error {
+	return toml.Unmarshal(b, v)
+}
+
+// envEncoder encode leaves as KEY=value lines, keys are upper-cased
+// path joined by underscore, e.g. SERVERS_0_HOST=localhost
+type envEncoder struct{}
+
+func (e *envEncoder) Encode(v interface{}) ([]byte, error) {
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, errors.New("env: root value must be an object")
+	}
+
+	vars := make(map[string]envVar)
+	if err := envVars(v, "", nil, vars); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(k + "=" + envQuote(vars[k].val) + "\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode read KEY=value lines into flat map of string values
+func (e *envEncoder) Decode(b []byte, v interface{}) error {
+	vars := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			return fmt.Errorf("env: line %d: missing =", n)
+		}
+
+		val := strings.TrimSpace(line[i+1:])
+		if strings.HasPrefix(val, `"`) {
+			s, err := strconv.Unquote(val)
+			if err != nil {
+				return fmt.Errorf("env: line %d: %v", n, err)
+			}
+
+			val = s
+		}
+
+		vars[strings.TrimSpace(line[:i])] = val
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// envVar is env value along with config path it is encoded from
+type envVar struct {
+	path string
+	val  string
+}
+
+// envVars collect leaves of tree by their env key, paths mapped
+// to the same key are rejected instead of overwriting each other
+func envVars(v interface{}, prefix string, segs []pathSegment, vars map[string]envVar) error {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(val) {
+			if err := envVars(val[k], envKey(prefix, k), appendSegment(segs, pathSegment{key: k}), vars); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range val {
+			if err := envVars(child, envKey(prefix, strconv.Itoa(i)), appendSegment(segs, pathSegment{index: i, isIndex: true}), vars); err != nil {
+				return err
+			}
+		}
+	default:
+		path := formatPath(segs)
+		if prev, ok := vars[prefix]; ok {
+			return fmt.Errorf("env: %s and %s map to the same key %s", prev.path, path, prefix)
+		}
+
+		str := ""
+		if val != nil {
+			str = fmt.Sprint(val)
+		}
+
+		vars[prefix] = envVar{path: path, val: str}
+	}
+
+	return nil
+}
+
+// envKey append key to env key prefix, characters other than
+// letters and digits are replaced by underscore
+func envKey(prefix, key string) string {
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "_" + key
+}
+
+// envQuote quote value when it contains whitespace or special characters
+func envQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\r\n\"'\\#$=`") {
+		return s
+	}
+
+	return strconv.Quote(s)
+}
+
+// plainTree convert json.Number of decoded json tree into int64 or float64,
+// so encoders other than json don't treat them as string
+func plainTree(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			m[k] = plainTree(child)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for i, child := range val {
+			l[i] = plainTree(child)
+		}
+		return l
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+
+		if u, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			return u
+		}
+
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+
+		return val.String()
+	default:
+		return v
+	}
+}
+
+// omitNil remove nil values from objects of decoded json tree
+func omitNil(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if child == nil {
+				delete(val, k)
+				continue
+			}
+
+			val[k] = omitNil(child)
+		}
+	case []interface{}:
+		for i, child := range val {
+			val[i] = omitNil(child)
+		}
+	}
+
+	return v
+}
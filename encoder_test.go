@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegisterEncoderConcurrentExport(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{"a": 1})))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+			RegisterEncoder(fmt.Sprint("test", i), &jsonEncoder{})
+		}(i)
+
+		go func() {
+			defer wg.Done()
+			if _, err := c.Export("json"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if _, err := c.Export("TEST0"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportRedactNormalizedKeys(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"Database": map[string]interface{}{"Host": "a", "Password": "secret"},
+	})), WithKeyNormalizer(LowerCaseKeys))
+
+	b, err := c.Export("json", Redact("Database.Password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"database":{"host":"a","password":"[REDACTED]"}}`; compactJSON(t, b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+
+	b, err = c.Sub("DATABASE").Export("json", Redact("PASSWORD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"host":"a","password":"[REDACTED]"}`; compactJSON(t, b) != want {
+		t.Fatalf("sub: got %s, want %s", b, want)
+	}
+}
+
+func newExportConfig() Config {
+	c := New(WithSource(Bytes([]byte(`{
+		"name": "app",
+		"port": 8080,
+		"big": 9007199254740993,
+		"ratio": 1.5,
+		"debug": true,
+		"tags": ["a", "b"],
+		"db": {"host": "h", "pass": "p w#1"}
+	}`), "json")))
+
+	c.Set(nil, "none")
+
+	return c
+}
+
+func TestExportFormats(t *testing.T) {
+	c := newExportConfig()
+
+	tests := map[string]string{
+		"json": `{
+  "big": 9007199254740993,
+  "db": {
+    "host": "h",
+    "pass": "p w#1"
+  },
+  "debug": true,
+  "name": "app",
+  "none": null,
+  "port": 8080,
+  "ratio": 1.5,
+  "tags": [
+    "a",
+    "b"
+  ]
+}
+`,
+		"yaml": `big: 9007199254740993
+db:
+    host: h
+    pass: p w#1
+debug: true
+name: app
+none: null
+port: 8080
+ratio: 1.5
+tags:
+  - a
+  - b
+`,
+		"toml": `big = 9007199254740993
+debug = true
+name = "app"
+port = 8080
+ratio = 1.5
+tags = ["a", "b"]
+
+[db]
+  host = "h"
+  pass = "p w#1"
+`,
+		"env": `BIG=9007199254740993
+DB_HOST=h
+DB_PASS="p w#1"
+DEBUG=true
+NAME=app
+NONE=""
+PORT=8080
+RATIO=1.5
+TAGS_0=a
+TAGS_1=b
+`,
+	}
+
+	for format, want := range tests {
+		b, err := c.Export(strings.ToUpper(format))
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		if string(b) != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", format, b, want)
+		}
+	}
+
+	if _, err := c.Export("xml"); err == nil {
+		t.Fatal("unsupported format exported")
+	}
+}
+
+func TestExportRedact(t *testing.T) {
+	c := newExportConfig()
+
+	b, err := c.Export("env", Redact("db.pass", "tags[-1]", "missing.key"), RedactKeys("PORT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `BIG=9007199254740993
+DB_HOST=h
+DB_PASS=[REDACTED]
+DEBUG=true
+NAME=app
+NONE=""
+PORT=[REDACTED]
+RATIO=1.5
+TAGS_0=a
+TAGS_1=[REDACTED]
+`
+	if string(b) != want {
+		t.Fatalf("got\n%s\nwant\n%s", b, want)
+	}
+
+	// redacted object is replaced as whole
+	b, err = c.Export("json", RedactKeys("db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(b), `"db": "[REDACTED]"`) {
+		t.Fatalf("got %s", b)
+	}
+
+	// export doesn't modify config
+	if got := c.Get("db", "pass").String(""); got != "p w#1" {
+		t.Fatalf("redacted config value %q", got)
+	}
+
+	if _, err := c.Export("json", Redact("a[")); err == nil {
+		t.Fatal("invalid redacted path accepted")
+	}
+}
+
+func TestExportNonObjectRoot(t *testing.T) {
+	c := newExportConfig()
+
+	for _, path := range []string{"tags", "name"} {
+		for _, format := range []string{"env", "toml"} {
+			if b, err := c.Sub(path).Export(format); err == nil {
+				t.Errorf("%s of %s: got %s, want error", format, path, b)
+			}
+		}
+	}
+
+	b, err := c.Sub("tags").Export("yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "- a\n- b\n" {
+		t.Fatalf("got %s", b)
+	}
+}
+
+func TestExportEnvKeyCollision(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"a_b": 1,
+		"a":   map[string]interface{}{"b": 2},
+	})))
+
+	_, err := c.Export("env")
+	if err == nil || err.Error() != "env: a.b and a_b map to the same key A_B" {
+		t.Fatalf("got error %v", err)
+	}
+
+	c = New(WithSource(Memory(map[string]interface{}{
+		"list": []interface{}{1},
+		"List": map[string]interface{}{"0": 2},
+	})))
+
+	if _, err := c.Export("env"); err == nil {
+		t.Fatal("colliding keys exported")
+	}
+}
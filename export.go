@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// redacted replace value of redacted path in exported config
+const redacted = "[REDACTED]"
+
+// ExportOption define method to modify export options
+type ExportOption func(o *exportOptions)
+
+type exportOptions struct {
+	// path expressions of redacted values
+	paths []string
+
+	// lower-cased key names redacted at any depth
+	keys []string
+
+	// normalizer of keys of redacted paths
+	normalize KeyNormalizer
+}
+
+// Redact replace values of given path expressions with placeholder,
+// paths are relative to exported config
+func Redact(paths ...string) ExportOption {
+	return func(o *exportOptions) {
+		o.paths = append(o.paths, paths...)
+	}
+}
+
+// RedactKeys replace values of keys with given names at any depth
+// with placeholder, names are compared case-insensitively
+func RedactKeys(keys ...string) ExportOption {
+	return func(o *exportOptions) {
+		for _, k := range keys {
+			o.keys = append(o.keys, strings.ToLower(k))
+		}
+	}
+}
+
+// exportNormalizer normalize keys of redacted paths as lookups are
+func exportNormalizer(normalize KeyNormalizer) ExportOption {
+	return func(o *exportOptions) {
+		o.normalize = normalize
+	}
+}
+
+// export encode tree in given format with redaction applied
+func export(tree interface{}, format string, opts ...ExportOption) ([]byte, error) {
+	enc, ok := encoder(format)
+	if !ok {
+		return nil, fmt.Errorf("config: unsupported export format %q", format)
+	}
+
+	var o exportOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(o.keys) > 0 {
+		tree = redactKeys(tree, o.keys)
+	}
+
+	for _, path := range o.paths {
+		segs, err := parsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("config: %v", err)
+		}
+
+		normalizeSegments(segs, o.normalize)
+
+		if _, ok := getSegments(tree, segs); ok {
+			tree = setSegments(tree, segs, redacted)
+		}
+	}
+
+	if tree == nil {
+		tree = map[string]interface{}{}
+	}
+
+	return enc.Encode(tree)
+}
+
+// redactKeys replace values of matched keys in decoded json tree
+func redactKeys(v interface{}, keys []string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if containsString(keys, strings.ToLower(k)) {
+				val[k] = redacted
+				continue
+			}
+
+			val[k] = redactKeys(child, keys)
+		}
+	case []interface{}:
+		for i, child := range val {
+			val[i] = redactKeys(child, keys)
+		}
+	}
+
+	return v
+}
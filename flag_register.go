@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fieldFlag is flag value of struct field, setting the field through reflection
+// so named types like `type Mode string` are supported along with builtin types
+type fieldFlag struct {
+	val reflect.Value
+}
+
+func (f *fieldFlag) String() string {
+	// zero flag value is created by flag package to detect default
+	if !f.val.IsValid() {
+		return ""
+	}
+
+	if f.val.Kind() == reflect.Slice {
+		items := make([]string, f.val.Len())
+		for i := range items {
+			items[i] = f.val.Index(i).String()
+		}
+
+		return strings.Join(items, ",")
+	}
+
+	return fmt.Sprint(f.val.Interface())
+}
+
+func (f *fieldFlag) Set(s string) error {
+	switch f.val.Kind() {
+	case reflect.String:
+		f.val.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		f.val.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, f.val.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		f.val.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 0, f.val.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		f.val.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(s, f.val.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		f.val.SetFloat(fl)
+	case reflect.Slice:
+		// comma separated list
+		items := strings.Split(s, ",")
+
+		l := reflect.MakeSlice(f.val.Type(), len(items), len(items))
+		for i, item := range items {
+			l.Index(i).SetString(item)
+		}
+
+		f.val.Set(l)
+	default:
+		return fmt.Errorf("unsupported flag type %s", f.val.Type())
+	}
+
+	return nil
+}
+
+func (f *fieldFlag) Get() interface{} {
+	return f.val.Interface()
+}
+
+// IsBoolFlag allow bool flag to be set without value, e.g. -verbose
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.val.IsValid() && f.val.Kind() == reflect.Bool
+}
+
+// RegisterFlags register flag for every leaf field of given struct pointer,
+// flag name is dotted path of field names taken from `flag` or `json` tag,
+// help text is taken from `usage` tag and default value is current field value.
+// Tag value "-" skip the field.
+//
+// Parsed flags are written into the struct and can be loaded with CliFlagSet
+func RegisterFlags(fs *flag.FlagSet, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("config: RegisterFlags requires non-nil pointer to struct")
+	}
+
+	return registerStructFlags(fs, rv.Elem(), "")
+}
+
+func registerStructFlags(fs *flag.FlagSet, rv reflect.Value, prefix string) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		// unexported field
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := flagName(field)
+		if name == "-" {
+			continue
+		}
+
+		// embedded struct fields are promoted like encoding/json does
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag == "" {
+			if err := registerStructFlags(fs, rv.Field(i), prefix); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		if err := registerFlag(fs, rv.Field(i), name, field.Tag.Get("usage")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func registerFlag(fs *flag.FlagSet, fv reflect.Value, name, usage string) error {
+	if fv.Type() == durationType {
+		ptr := fv.Addr().Interface().(*time.Duration)
+		fs.DurationVar(ptr, name, *ptr, usage)
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		return registerStructFlags(fs, fv, name)
+	case reflect.Ptr:
+		if fv.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+
+		return registerStructFlags(fs, fv.Elem(), name)
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		fs.Var(&fieldFlag{val: fv}, name, usage)
+		return nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			break
+		}
+
+		fs.Var(&fieldFlag{val: fv}, name, usage)
+		return nil
+	}
+
+	return fmt.Errorf("config: unsupported flag type %s of %s", fv.Type(), name)
+}
+
+// flagName return flag name of struct field
+func flagName(field reflect.StructField) string {
+	for _, tag := range []string{"flag", "json"} {
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name != "" {
+			return name
+		}
+	}
+
+	return strings.ToLower(field.Name)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type mode string
+
+type names []string
+
+func TestRegisterFlagsNamedTypes(t *testing.T) {
+	var v struct {
+		Mode    mode          `flag:"mode"`
+		Names   names         `flag:"names"`
+		Verbose bool          `flag:"verbose"`
+		Level   int8          `flag:"level"`
+		Port    uint16        `flag:"port"`
+		Rate    float32       `flag:"rate"`
+		Timeout time.Duration `flag:"timeout"`
+		Nested  struct {
+			ID int32 `flag:"id"`
+		} `flag:"nested"`
+	}
+
+	v.Mode = "dev"
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := RegisterFlags(fs, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if def := fs.Lookup("mode").DefValue; def != "dev" {
+		t.Fatalf("mode default = %q, want dev", def)
+	}
+
+	err := fs.Parse([]string{
+		"-mode", "prod", "-names", "a,b", "-verbose", "-level", "-3",
+		"-port", "8080", "-rate", "0.5", "-timeout", "2s", "-nested.id", "7",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Mode != "prod" || !reflect.DeepEqual(v.Names, names{"a", "b"}) || !v.Verbose ||
+		v.Level != -3 || v.Port != 8080 || v.Rate != 0.5 || v.Timeout != 2*time.Second || v.Nested.ID != 7 {
+		t.Fatalf("unexpected values %+v", v)
+	}
+
+	if err := fs.Parse([]string{"-level", "200"}); err == nil {
+		t.Fatal("expected out of range error for int8 flag")
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Revision is merged snapshot kept in config history
+type Revision struct {
+	// Version is sequence number of merged snapshot
+	Version uint64
+
+	// Snapshot is merged snapshot of the revision
+	Snapshot *Snapshot
+
+	// Changes from previous revision
+	Changes []Change
+}
+
+// Change describe changed config path between revisions
+type Change struct {
+	Path string
+
+	// Old is previous value, nil when path is added
+	Old interface{}
+
+	// New is current value, nil when path is removed
+	New interface{}
+}
+
+// history is bounded ring of merged snapshots
+type history struct {
+	size      int
+	revisions []*Revision
+
+	// latest version
+	version uint64
+}
+
+// add record new merged snapshot and return its revision
+func (h *history) add(snap *Snapshot) *Revision {
+	h.version++
+
+	rev := &Revision{
+		Version:  h.version,
+		Snapshot: snap,
+	}
+
+	if last := h.latest(); last != nil {
+		rev.Changes = diffSnapshots(last.Snapshot, snap)
+	}
+
+	if h.size <= 0 {
+		return rev
+	}
+
+	h.revisions = append(h.revisions, rev)
+	if len(h.revisions) > h.size {
+		h.revisions = h.revisions[len(h.revisions)-h.size:]
+	}
+
+	return rev
+}
+
+// get return revision of given version, nil if not found
+func (h *history) get(version uint64) *Revision {
+	for _, rev := range h.revisions {
+		if rev.Version == version {
+			return rev
+		}
+	}
+
+	return nil
+}
+
+// latest return latest revision, nil if history is empty
+func (h *history) latest() *Revision {
+	if len(h.revisions) == 0 {
+		return nil
+	}
+
+	return h.revisions[len(h.revisions)-1]
+}
+
+// list return copy of kept revisions, oldest first
+func (h *history) list() []Revision {
+	res := make([]Revision, len(h.revisions))
+	for i, rev := range h.revisions {
+		res[i] = *rev
+	}
+
+	return res
+}
+
+// diffSnapshots compare leaf values of two snapshots
+func diffSnapshots(prev, next *Snapshot) []Change {
+	old := make(map[string]interface{})
+	flattenLeaves(snapshotTree(prev), "", old)
+
+	cur := make(map[string]interface{})
+	flattenLeaves(snapshotTree(next), "", cur)
+
+	// leaves of parsed tree are shared with snapshot, changes hold copies
+	changes := make([]Change, 0)
+	for path, v := range old {
+		n, ok := cur[path]
+		if !ok {
+			changes = append(changes, Change{Path: path, Old: copyTree(v)})
+			continue
+		}
+
+		if !reflect.DeepEqual(v, n) {
+			changes = append(changes, Change{Path: path, Old: copyTree(v), New: copyTree(n)})
+		}
+	}
+
+	for path, v := range cur {
+		if _, ok := old[path]; !ok {
+			changes = append(changes, Change{Path: path, New: copyTree(v)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// snapshotTree return decoded tree of snapshot, parsed representation
+// of merged snapshot is used instead of decoding its data
+func snapshotTree(snap *Snapshot) interface{} {
+	switch {
+	case snap == nil:
+		return nil
+	case snap.parsed != nil:
+		return snap.parsed
+	case snap.tree != nil:
+		return snap.tree.value()
+	}
+
+	var v interface{}
+	decodeJSON(snap.Data, &v)
+
+	return v
+}
+
+// flattenLeaves collect non-object values of tree by dotted path,
+// arrays are compared as whole value
+func flattenLeaves(v interface{}, prefix string, dest map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		if prefix != "" {
+			dest[prefix] = v
+		}
+
+		return
+	}
+
+	for k, child := range m {
+		path := formatKey(k)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		flattenLeaves(child, path, dest)
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"testing"
+)
+
+// newHistoryConfig return config with given history size
+// and revision of every value of a
+func newHistoryConfig(t *testing.T, size int, values ...int) (*config, MemoryLoader) {
+	src := Memory(map[string]interface{}{"a": values[0]})
+	c := New(WithSource(src), WithHistory(size)).(*config)
+
+	for _, v := range values[1:] {
+		if err := src.Update(map[string]interface{}{"a": v}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.readAndMergeConfigs(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return c, src
+}
+
+func TestRollbackPinUntilSourceChange(t *testing.T) {
+	c, src := newHistoryConfig(t, 10, 1, 2, 3)
+
+	hist := c.History()
+	if len(hist) != 3 {
+		t.Fatalf("got %d revisions", len(hist))
+	}
+
+	sub := c.Subscribe()
+
+	if err := c.Rollback(hist[0].Version); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-sub:
+	default:
+		t.Fatal("rollback didn't notify")
+	}
+
+	if got := c.Get("a").Int(0); got != 1 {
+		t.Fatalf("got %d after rollback, want 1", got)
+	}
+
+	// unchanged sources keep the pin
+	if err := c.readAndMergeConfigs(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Get("a").Int(0); got != 1 {
+		t.Fatalf("got %d after reload, want pinned 1", got)
+	}
+
+	// new change release the pin
+	if err := src.Update(map[string]interface{}{"a": 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.readAndMergeConfigs(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Get("a").Int(0); got != 4 {
+		t.Fatalf("got %d after change, want 4", got)
+	}
+
+	if c.pinned != nil {
+		t.Fatal("pin kept after change")
+	}
+
+	// rolled back revision is not added to history
+	if hist = c.History(); len(hist) != 4 {
+		t.Fatalf("got %d revisions", len(hist))
+	}
+}
+
+func TestReleaseRestoreLatest(t *testing.T) {
+	c, _ := newHistoryConfig(t, 10, 1, 2)
+
+	// release without pin is no-op
+	if err := c.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Rollback(c.History()[0].Version); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Get("a").Int(0); got != 2 {
+		t.Fatalf("got %d after release, want 2", got)
+	}
+
+	if c.pinned != nil {
+		t.Fatal("pin kept after release")
+	}
+}
+
+func TestRollbackUnknownVersion(t *testing.T) {
+	c, _ := newHistoryConfig(t, 2, 1, 2, 3)
+
+	hist := c.History()
+	if len(hist) != 2 || hist[0].Version != 2 {
+		t.Fatalf("got revisions %+v", hist)
+	}
+
+	// evicted and future versions
+	for _, v := range []uint64{0, 1, 4} {
+		if err := c.Rollback(v); err == nil {
+			t.Fatalf("rollback to unknown version %d", v)
+		}
+	}
+
+	if got := c.Get("a").Int(0); got != 3 {
+		t.Fatalf("got %d, want 3", got)
+	}
+}
+
+func TestHistoryDisabled(t *testing.T) {
+	c, _ := newHistoryConfig(t, 0, 1, 2)
+
+	if hist := c.History(); len(hist) != 0 {
+		t.Fatalf("got %d revisions", len(hist))
+	}
+
+	if err := c.Rollback(1); err == nil {
+		t.Fatal("rollback without history")
+	}
+
+	if err := c.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Get("a").Int(0); got != 2 {
+		t.Fatalf("got %d, want 2", got)
+	}
+}
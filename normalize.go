@@ -0,0 +1,232 @@
+package config
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// KeyNormalizer convert config key into its canonical form,
+// keys of every source and path of lookups are normalized before use
+type KeyNormalizer func(key string) string
+
+// LowerCaseKeys normalize keys to lower case, e.g. Database.Host to database.host
+func LowerCaseKeys(key string) string {
+	return strings.ToLower(key)
+}
+
+// SnakeCaseKeys normalize camelCase, PascalCase and kebab-case keys
+// to snake_case, e.g. poolSize and pool-size to pool_size
+func SnakeCaseKeys(key string) string {
+	var b strings.Builder
+
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteRune('_')
+		case unicode.IsUpper(r):
+			// word boundary: fooBar, or last upper of acronym in HTTPServer
+			if i > 0 && runes[i-1] != '_' && runes[i-1] != '-' &&
+				(unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+					(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteRune('_')
+			}
+
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// FoldCaseKeys fold case and drop word separators so that poolSize,
+// PoolSize, pool_size and pool-size are all the same key
+func FoldCaseKeys(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+
+		return unicode.ToLower(r)
+	}, key)
+}
+
+// normalizeSnapshot return copy of snapshot with normalized keys,
+// parsed tree of snapshot is normalized instead of decoding its data
+func normalizeSnapshot(snap *Snapshot, normalize KeyNormalizer) (*Snapshot, error) {
+	if snap == nil || len(snap.Data) == 0 {
+		return snap, nil
+	}
+
+	if snap.tree != nil {
+		n := normalizeNode(snap.tree, normalize)
+
+		b, err := n.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Snapshot{
+			Data:       b,
+			Metadata:   snap.Metadata,
+			Components: snap.Components,
+			tree:       n,
+		}, nil
+	}
+
+	data := snap.parsed
+	if data == nil {
+		if err := decodeJSON(snap.Data, &data); err != nil {
+			return nil, err
+		}
+	}
+
+	data = normalizeTree(data, normalize)
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Data:       b,
+		Metadata:   snap.Metadata,
+		Components: snap.Components,
+		parsed:     data,
+	}, nil
+}
+
+// normalizeTree normalize object keys of decoded json tree, objects
+// whose keys collide after normalization are merged in key order
+func normalizeTree(node interface{}, normalize KeyNormalizer) interface{} {
+	switch val := node.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for _, k := range sortedKeys(val) {
+			nk := normalize(k)
+			child := normalizeTree(val[k], normalize)
+
+			prev, pok := m[nk].(map[string]interface{})
+			next, nok := child.(map[string]interface{})
+			if pok && nok {
+				for ck, cv := range next {
+					prev[ck] = cv
+				}
+
+				continue
+			}
+
+			m[nk] = child
+		}
+
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for i, item := range val {
+			l[i] = normalizeTree(item, normalize)
+		}
+
+		return l
+	default:
+		return node
+	}
+}
+
+// normalizeNode is normalizeTree of ordered tree, normalized keys
+// keep source order of their first occurrence
+func normalizeNode(n *node, normalize KeyNormalizer) *node {
+	switch n.kind {
+	case objectNode:
+		res := &node{kind: objectNode, fields: make(map[string]*node, len(n.fields)), meta: n.meta}
+
+		// colliding keys are merged in key order like normalizeTree
+		keys := append([]string(nil), n.keys...)
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			nk := normalize(k)
+			child := normalizeNode(n.fields[k], normalize)
+
+			prev, ok := res.fields[nk]
+			if ok && prev.kind == objectNode && child.kind == objectNode {
+				merged := &node{
+					kind:   objectNode,
+					keys:   append([]string(nil), prev.keys...),
+					fields: make(map[string]*node, len(prev.fields)+len(child.fields)),
+					meta:   child.meta,
+				}
+
+				for ck, cv := range prev.fields {
+					merged.fields[ck] = cv
+				}
+
+				for _, ck := range child.keys {
+					if _, ok := merged.fields[ck]; !ok {
+						merged.keys = append(merged.keys, ck)
+					}
+
+					merged.fields[ck] = child.fields[ck]
+				}
+
+				res.fields[nk] = merged
+				continue
+			}
+
+			res.fields[nk] = child
+		}
+
+		seen := make(map[string]bool, len(res.fields))
+		res.keys = make([]string, 0, len(res.fields))
+		for _, k := range n.keys {
+			if nk := normalize(k); !seen[nk] {
+				seen[nk] = true
+				res.keys = append(res.keys, nk)
+			}
+		}
+
+		return res
+	case arrayNode:
+		res := &node{kind: arrayNode, items: make([]*node, len(n.items)), meta: n.meta}
+		for i, item := range n.items {
+			res.items[i] = normalizeNode(item, normalize)
+		}
+
+		return res
+	default:
+		return n
+	}
+}
+
+// normalizePath return path expression with normalized keys,
+// invalid path is returned as is to surface its error on lookup
+func normalizePath(path []string, normalize KeyNormalizer) []string {
+	if normalize == nil {
+		return path
+	}
+
+	segs, err := resolvePath(path)
+	if err != nil {
+		return path
+	}
+
+	normalizeSegments(segs, normalize)
+
+	return []string{formatPath(segs)}
+}
+
+// normalizeSegments normalize keys of path segments in place
+func normalizeSegments(segs []pathSegment, normalize KeyNormalizer) {
+	if normalize == nil {
+		return
+	}
+
+	for i := range segs {
+		if !segs[i].isIndex {
+			segs[i].key = normalize(segs[i].key)
+		}
+	}
+}
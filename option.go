@@ -18,6 +18,18 @@ type Options struct {
 	// source loaders
 	sources []Loader
 
+	// number of merged snapshots kept in history
+	historySize int
+
+	// last known good snapshot cache
+	cache *fileCache
+
+	// key normalizer applied to sources and lookups
+	normalizer KeyNormalizer
+
+	// renamed keys resolved to their new path
+	aliases []*alias
+
 	// watcher should be configured along with running context
 	watch         bool
 	watchDuration time.Duration
@@ -49,6 +61,34 @@ func WithSource(loader Loader, decoder ...Decoder) Option {
 	}
 }
 
+// WithHistory set number of previous merged snapshots kept for rollback,
+// zero disable history
+func WithHistory(size int) Option {
+	return func(o *Options) {
+		o.historySize = size
+	}
+}
+
+// WithCache persist last merged snapshot to given file,
+// which is used on startup when sources failed to load.
+// Optional codec encode cached stream, e.g. to encrypt it
+func WithCache(file string, codec ...Codec) Option {
+	return func(o *Options) {
+		o.cache = &fileCache{file: file}
+		if len(codec) > 0 {
+			o.cache.codec = codec[0]
+		}
+	}
+}
+
+// WithKeyNormalizer normalize keys of every source before merged
+// and path of every lookup, e.g. LowerCaseKeys for case-insensitive keys
+func WithKeyNormalizer(normalizer KeyNormalizer) Option {
+	return func(o *Options) {
+		o.normalizer = normalizer
+	}
+}
+
 // WithReader overrides default config reader
 func WithReader(reader Reader) Option {
 	return func(o *Options) {
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"sort"
+	"time"
+)
+
+// OverrideOption define method to modify runtime override options
+type OverrideOption func(o *overrideOptions)
+
+type overrideOptions struct {
+	ttl time.Duration
+}
+
+// WithTTL expire runtime override after given duration
+func WithTTL(d time.Duration) OverrideOption {
+	return func(o *overrideOptions) {
+		o.ttl = d
+	}
+}
+
+// override is runtime value of config path
+type override struct {
+	val   interface{}
+	timer *time.Timer
+}
+
+func (o *override) stop() {
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+}
+
+// applyOverrides set overrides value, parent path first
+func applyOverrides(values Values, overrides map[string]*override) {
+	paths := make([]string, 0, len(overrides))
+	for path := range overrides {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		values.Set(overrides[path].val, path)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func newOverrideConfig() (*config, MemoryLoader) {
+	src := Memory(map[string]interface{}{
+		"db": map[string]interface{}{"host": "a", "port": 1},
+	})
+
+	return New(WithSource(src)).(*config), src
+}
+
+func TestOverrideSurviveReload(t *testing.T) {
+	c, src := newOverrideConfig()
+
+	c.Override("db.host", "b")
+
+	if err := src.Update(map[string]interface{}{
+		"db": map[string]interface{}{"host": "c", "port": 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.readAndMergeConfigs(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.Get("db", "host").String(""); got != "b" {
+		t.Fatalf("override lost on reload, got host %q", got)
+	}
+
+	if got := c.Get("db", "port").Int(0); got != 2 {
+		t.Fatalf("reloaded value is not applied, got port %d", got)
+	}
+
+	c.RemoveOverride("db.host")
+
+	if got := c.Get("db", "host").String(""); got != "c" {
+		t.Fatalf("got host %q after override is removed", got)
+	}
+}
+
+func TestOverrideTTLExpire(t *testing.T) {
+	c, _ := newOverrideConfig()
+	sub := c.Subscribe()
+
+	c.Override("db.host", "b", WithTTL(time.Millisecond*20))
+
+	select {
+	case <-sub:
+	default:
+		t.Fatal("override is not notified")
+	}
+
+	if got := c.Get("db", "host").String(""); got != "b" {
+		t.Fatalf("got host %q", got)
+	}
+
+	select {
+	case <-sub:
+	case <-time.After(time.Second * 5):
+		t.Fatal("expiration is not notified")
+	}
+
+	if got := c.Get("db", "host").String(""); got != "a" {
+		t.Fatalf("got host %q after ttl", got)
+	}
+
+	c.RLock()
+	n := len(c.overrides)
+	c.RUnlock()
+
+	if n != 0 {
+		t.Fatalf("%d overrides left after ttl", n)
+	}
+}
+
+func TestOverrideReplaceStopTimer(t *testing.T) {
+	c, _ := newOverrideConfig()
+
+	c.Override("db.host", "b", WithTTL(time.Millisecond*20))
+
+	c.RLock()
+	prev := c.overrides["db.host"]
+	c.RUnlock()
+
+	c.Override("db.host", "c")
+
+	// stopped timer can't be stopped again
+	if prev.timer.Stop() {
+		t.Fatal("timer of replaced override is running")
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	if got := c.Get("db", "host").String(""); got != "c" {
+		t.Fatalf("replacing override expired, got host %q", got)
+	}
+}
@@ -0,0 +1,396 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is single step of config path, either object key or array index
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// parsePath parse path expression shared by Get, Set and Del:
+//
+//	database.host           object keys separated by dot
+//	servers[0].host         array index, negative index count from the end
+//	hosts."example.com"     quoted key, either double or single quoted
+//	hosts["example.com"]    quoted key in brackets
+//	hosts.example\.com      escaped dot, backslash escape any next character
+func parsePath(s string) ([]pathSegment, error) {
+	segs := make([]pathSegment, 0)
+
+	// whether next segment is expected to be a key
+	expectKey := !strings.HasPrefix(s, "[")
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '[':
+			seg, n, err := parseBracket(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q at %d: %v", s, i, err)
+			}
+
+			segs = append(segs, seg)
+			i += n
+			expectKey = false
+		case s[i] == '.' && !expectKey:
+			i++
+			expectKey = true
+		case expectKey:
+			key, n, err := parseKey(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q at %d: %v", s, i, err)
+			}
+
+			segs = append(segs, pathSegment{key: key})
+			i += n
+			expectKey = false
+		default:
+			return nil, fmt.Errorf("invalid path %q at %d: unexpected %q", s, i, s[i])
+		}
+	}
+
+	if expectKey && len(s) > 0 {
+		return nil, fmt.Errorf("invalid path %q: missing key after dot", s)
+	}
+
+	return segs, nil
+}
+
+// parseKey parse bare or quoted key, return key and consumed length
+func parseKey(s string) (string, int, error) {
+	if s[0] == '"' || s[0] == '\'' {
+		return parseQuoted(s)
+	}
+
+	var b strings.Builder
+
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' {
+		if s[i] == '\\' {
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("trailing backslash")
+			}
+
+			i++
+		}
+
+		b.WriteByte(s[i])
+		i++
+	}
+
+	if i == 0 {
+		return "", 0, fmt.Errorf("empty key")
+	}
+
+	return b.String(), i, nil
+}
+
+// parseQuoted parse quoted key, return key and consumed length
+func parseQuoted(s string) (string, int, error) {
+	quote := s[0]
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 >= len(s) {
+				return "", 0, fmt.Errorf("trailing backslash")
+			}
+
+			i++
+			b.WriteByte(s[i])
+		case quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated quote")
+}
+
+// parseBracket parse [n] index or quoted key in brackets, return segment and consumed length
+func parseBracket(s string) (pathSegment, int, error) {
+	if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+		key, n, err := parseQuoted(s[1:])
+		if err != nil {
+			return pathSegment{}, 0, err
+		}
+
+		if 1+n >= len(s) || s[1+n] != ']' {
+			return pathSegment{}, 0, fmt.Errorf("missing closing bracket")
+		}
+
+		return pathSegment{key: key}, n + 2, nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathSegment{}, 0, fmt.Errorf("missing closing bracket")
+	}
+
+	index, err := strconv.Atoi(s[1:end])
+	if err != nil {
+		return pathSegment{}, 0, fmt.Errorf("invalid index %q", s[1:end])
+	}
+
+	return pathSegment{index: index, isIndex: true}, end + 1, nil
+}
+
+// formatPath return canonical path expression of segments,
+// keys containing special characters are quoted
+func formatPath(segs []pathSegment) string {
+	var b strings.Builder
+
+	for i, seg := range segs {
+		if seg.isIndex {
+			b.WriteString("[" + strconv.Itoa(seg.index) + "]")
+			continue
+		}
+
+		if i > 0 {
+			b.WriteByte('.')
+		}
+
+		b.WriteString(formatKey(seg.key))
+	}
+
+	return b.String()
+}
+
+// formatKey quote key when it can't be written as bare key
+func formatKey(key string) string {
+	if key != "" && !strings.ContainsAny(key, `.[]"'\`) {
+		return key
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"`
+}
+
+// joinPath return path expression of relative path under prefix expression
+func joinPath(prefix string, path []string) string {
+	rel := ""
+	switch {
+	case len(path) > 1:
+		// multiple arguments are literal keys
+		segs := make([]pathSegment, len(path))
+		for i, k := range path {
+			segs[i] = pathSegment{key: k}
+		}
+
+		rel = formatPath(segs)
+	case len(path) == 1:
+		rel = path[0]
+	}
+
+	if prefix == "" || rel == "" {
+		return prefix + rel
+	}
+
+	if strings.HasPrefix(rel, "[") {
+		return prefix + rel
+	}
+
+	return prefix + "." + rel
+}
+
+// resolvePath resolve path arguments into segments, single argument is
+// parsed as path expression while multiple arguments are literal keys
+func resolvePath(path []string) ([]pathSegment, error) {
+	if len(path) == 0 {
+		return []pathSegment{}, nil
+	}
+
+	if len(path) == 1 {
+		return parsePath(path[0])
+	}
+
+	segs := make([]pathSegment, len(path))
+	for i, k := range path {
+		segs[i] = pathSegment{key: k}
+	}
+
+	return segs, nil
+}
+
+// pathKeys return keys of path which consist of object keys only
+func pathKeys(segs []pathSegment) ([]string, error) {
+	keys := make([]string, len(segs))
+	for i, seg := range segs {
+		if seg.isIndex {
+			return nil, fmt.Errorf("array index is not supported in path %s", formatPath(segs))
+		}
+
+		keys[i] = seg.key
+	}
+
+	return keys, nil
+}
+
+// getSegments return value of path in decoded json tree
+func getSegments(node interface{}, segs []pathSegment) (interface{}, bool) {
+	for _, seg := range segs {
+		if seg.isIndex {
+			l, ok := node.([]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			i, ok := sliceIndex(len(l), seg.index)
+			if !ok {
+				return nil, false
+			}
+
+			node = l[i]
+			continue
+		}
+
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		node, ok = m[seg.key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+// setSegments return decoded json tree with value of path set, creating missing
+// objects and appending to arrays at index of their length, only objects and arrays
+// along the path are copied while the rest is shared with given tree. Tree is
+// returned unchanged when index of path is out of array bounds
+func setSegments(node interface{}, segs []pathSegment, val interface{}) interface{} {
+	res, ok := trySetSegments(node, segs, val)
+	if !ok {
+		return node
+	}
+
+	return res
+}
+
+// trySetSegments set value of path, reporting false for index out of bounds
+func trySetSegments(node interface{}, segs []pathSegment, val interface{}) (interface{}, bool) {
+	if len(segs) == 0 {
+		return val, true
+	}
+
+	seg := segs[0]
+	if seg.isIndex {
+		l, _ := node.([]interface{})
+
+		i, ok := sliceIndex(len(l), seg.index)
+		if !ok && i != len(l) {
+			// array is extended by appending only
+			return nil, false
+		}
+
+		child, ok := trySetSegments(nil, segs[1:], val)
+		if i < len(l) {
+			child, ok = trySetSegments(l[i], segs[1:], val)
+		}
+
+		if !ok {
+			return nil, false
+		}
+
+		res := make([]interface{}, len(l), len(l)+1)
+		copy(res, l)
+
+		if i == len(l) {
+			return append(res, child), true
+		}
+
+		res[i] = child
+		return res, true
+	}
+
+	m, _ := node.(map[string]interface{})
+
+	child, ok := trySetSegments(m[seg.key], segs[1:], val)
+	if !ok {
+		return nil, false
+	}
+
+	res := make(map[string]interface{}, len(m)+1)
+	for k, c := range m {
+		res[k] = c
+	}
+
+	res[seg.key] = child
+	return res, true
+}
+
+// delSegments return decoded json tree without given path, only objects and
+// arrays along the path are copied while the rest is shared with given tree
+func delSegments(node interface{}, segs []pathSegment) interface{} {
+	if len(segs) == 0 {
+		return nil
+	}
+
+	seg := segs[0]
+	last := len(segs) == 1
+
+	if seg.isIndex {
+		l, ok := node.([]interface{})
+		if !ok {
+			return node
+		}
+
+		i, ok := sliceIndex(len(l), seg.index)
+		if !ok {
+			return node
+		}
+
+		if last {
+			res := make([]interface{}, 0, len(l)-1)
+			return append(append(res, l[:i]...), l[i+1:]...)
+		}
+
+		res := make([]interface{}, len(l))
+		copy(res, l)
+
+		res[i] = delSegments(l[i], segs[1:])
+		return res
+	}
+
+	m, ok := node.(map[string]interface{})
+	if !ok {
+		return node
+	}
+
+	child, ok := m[seg.key]
+	if !ok {
+		return node
+	}
+
+	res := make(map[string]interface{}, len(m))
+	for k, c := range m {
+		res[k] = c
+	}
+
+	if last {
+		delete(res, seg.key)
+		return res
+	}
+
+	res[seg.key] = delSegments(child, segs[1:])
+	return res
+}
+
+// sliceIndex resolve negative index and check bounds
+func sliceIndex(length, index int) (int, bool) {
+	if index < 0 {
+		index += length
+	}
+
+	return index, index >= 0 && index < length
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	key := func(k string) pathSegment { return pathSegment{key: k} }
+	index := func(i int) pathSegment { return pathSegment{index: i, isIndex: true} }
+
+	tests := []struct {
+		path string
+		want []pathSegment
+	}{
+		{"", []pathSegment{}},
+		{"a", []pathSegment{key("a")}},
+		{"a.b.c", []pathSegment{key("a"), key("b"), key("c")}},
+		{`hosts."example.com".port`, []pathSegment{key("hosts"), key("example.com"), key("port")}},
+		{`hosts.'example.com'`, []pathSegment{key("hosts"), key("example.com")}},
+		{`a."q\"uote"`, []pathSegment{key("a"), key(`q"uote`)}},
+		{`a.""`, []pathSegment{key("a"), key("")}},
+		{`hosts.example\.com`, []pathSegment{key("hosts"), key("example.com")}},
+		{`a\\b`, []pathSegment{key(`a\b`)}},
+		{`a\[0]`, []pathSegment{key("a[0]")}},
+		{`hosts["example.com"]`, []pathSegment{key("hosts"), key("example.com")}},
+		{`hosts['a]b'].port`, []pathSegment{key("hosts"), key("a]b"), key("port")}},
+		{"servers[0].host", []pathSegment{key("servers"), index(0), key("host")}},
+		{"servers[-1]", []pathSegment{key("servers"), index(-1)}},
+		{"[1][2]", []pathSegment{index(1), index(2)}},
+		{"a[0].b[-2]", []pathSegment{key("a"), index(0), key("b"), index(-2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, path := range []string{
+		"a..b",
+		".a",
+		"a.",
+		"a[",
+		"a[0",
+		"a[x]",
+		"a[]",
+		`a["b"`,
+		`a["b"x]`,
+		`a."b`,
+		`a\`,
+		`a."b\`,
+		"a[0]b",
+	} {
+		if segs, err := parsePath(path); err == nil {
+			t.Errorf("%s: got %+v, want error", path, segs)
+		}
+	}
+}
+
+func TestFormatPathRoundTrip(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"a.b", "a.b"},
+		{"servers[-1].host", "servers[-1].host"},
+		{`hosts.example\.com`, `hosts."example.com"`},
+		{`hosts['example.com']`, `hosts."example.com"`},
+		{`a."q\"uote"`, `a."q\"uote"`},
+		{`a."back\\slash"`, `a."back\\slash"`},
+		{`a.""`, `a.""`},
+		{`a."[0]"`, `a."[0]"`},
+		{"[0].a", "[0].a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := formatPath(segs)
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+
+			again, err := parsePath(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(again, segs) {
+				t.Fatalf("round trip: got %+v, want %+v", again, segs)
+			}
+		})
+	}
+}
+
+func TestSetArrayIndexBound(t *testing.T) {
+	for _, reader := range []Option{WithReader(&jsonReader{}), WithReader(TreeReader())} {
+		c := New(WithSource(Memory(map[string]interface{}{"l": []interface{}{1, 2}})), reader)
+
+		c.Set(3, "l[2]")
+		c.Set(0, "l[-3]")
+		c.Set(9, "l[-4]")
+		c.Set(1, "l[100000000]")
+		c.Set(1, "m[1]")
+		c.Set("a", "n[0]")
+
+		if got := string(c.Bytes()); got != `{"l":[0,2,3],"n":["a"]}` {
+			t.Fatalf("%T: got %s", c.(*config).options.reader, got)
+		}
+	}
+}
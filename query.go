@@ -0,0 +1,675 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// queryStep is single selector of query expression
+type queryStep struct {
+	// recursive descent, select from node and all its descendants
+	recursive bool
+
+	wildcard bool
+	seg      *pathSegment
+	filter   filterExpr
+}
+
+// queryMatch is value selected by query along with its path
+type queryMatch struct {
+	val  interface{}
+	path []pathSegment
+}
+
+// query evaluate JSONPath-style expression over decoded json tree:
+//
+//	$.upstreams[0].host             child keys and array index
+//	$.upstreams[*].host             wildcard over object values or array items
+//	$..host                         recursive descent
+//	$.upstreams[?(@.region=='eu')]  filter with ==, !=, <, <=, >, >=, &&, || and !
+func query(tree interface{}, expr string) ([]queryMatch, error) {
+	steps, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return evalQuery(tree, steps), nil
+}
+
+// evalQuery select values of parsed query steps
+func evalQuery(tree interface{}, steps []queryStep) []queryMatch {
+	matches := []queryMatch{{val: tree, path: []pathSegment{}}}
+	for _, step := range steps {
+		if step.recursive {
+			matches = descendants(matches)
+		}
+
+		next := make([]queryMatch, 0)
+		for _, m := range matches {
+			next = append(next, step.apply(m)...)
+		}
+
+		matches = next
+	}
+
+	return matches
+}
+
+// normalizeQuery normalize keys of query steps and of path operands of their filters
+func normalizeQuery(steps []queryStep, normalize KeyNormalizer) {
+	if normalize == nil {
+		return
+	}
+
+	for _, step := range steps {
+		if step.seg != nil && !step.seg.isIndex {
+			step.seg.key = normalize(step.seg.key)
+		}
+
+		normalizeFilter(step.filter, normalize)
+	}
+}
+
+// normalizeFilter normalize keys of path operands of filter expression
+func normalizeFilter(expr filterExpr, normalize KeyNormalizer) {
+	switch e := expr.(type) {
+	case *pathOperand:
+		normalizeSegments(e.segs, normalize)
+	case *notExpr:
+		normalizeFilter(e.expr, normalize)
+	case *logicalExpr:
+		normalizeFilter(e.left, normalize)
+		normalizeFilter(e.right, normalize)
+	case *compareExpr:
+		normalizeFilter(e.left, normalize)
+		normalizeFilter(e.right, normalize)
+	}
+}
+
+// apply select children of matched value
+func (s *queryStep) apply(m queryMatch) []queryMatch {
+	if s.seg != nil {
+		v, ok := getSegments(m.val, []pathSegment{*s.seg})
+		if !ok {
+			return nil
+		}
+
+		seg := *s.seg
+		if seg.isIndex {
+			// resolve negative index for path of matched value
+			seg.index, _ = sliceIndex(len(m.val.([]interface{})), seg.index)
+		}
+
+		return []queryMatch{{val: v, path: appendSegment(m.path, seg)}}
+	}
+
+	res := make([]queryMatch, 0)
+	for _, child := range children(m) {
+		if s.filter != nil && !truthy(s.filter.eval(child.val)) {
+			continue
+		}
+
+		res = append(res, child)
+	}
+
+	return res
+}
+
+// children return object values ordered by key or array items
+func children(m queryMatch) []queryMatch {
+	switch val := m.val.(type) {
+	case map[string]interface{}:
+		keys := sortedKeys(val)
+
+		res := make([]queryMatch, len(keys))
+		for i, k := range keys {
+			res[i] = queryMatch{val: val[k], path: appendSegment(m.path, pathSegment{key: k})}
+		}
+
+		return res
+	case []interface{}:
+		res := make([]queryMatch, len(val))
+		for i, v := range val {
+			res[i] = queryMatch{val: v, path: appendSegment(m.path, pathSegment{index: i, isIndex: true})}
+		}
+
+		return res
+	default:
+		return nil
+	}
+}
+
+// descendants return matches along with all their descendants, depth first
+func descendants(matches []queryMatch) []queryMatch {
+	res := make([]queryMatch, 0)
+	for _, m := range matches {
+		res = append(res, m)
+		res = append(res, descendants(children(m))...)
+	}
+
+	return res
+}
+
+func appendSegment(path []pathSegment, seg pathSegment) []pathSegment {
+	return append(append(make([]pathSegment, 0, len(path)+1), path...), seg)
+}
+
+// parseQuery parse query expression into steps
+func parseQuery(expr string) ([]queryStep, error) {
+	s := strings.TrimSpace(expr)
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("invalid query %q: must start with $", expr)
+	}
+
+	steps := make([]queryStep, 0)
+	for i := 1; i < len(s); {
+		var step queryStep
+
+		switch {
+		case strings.HasPrefix(s[i:], ".."):
+			step.recursive = true
+			i += 2
+
+			// ..[selector]
+			if i < len(s) && s[i] == '[' {
+				n, err := parseQueryBracket(s[i:], &step)
+				if err != nil {
+					return nil, fmt.Errorf("invalid query %q at %d: %v", expr, i, err)
+				}
+
+				i += n
+				break
+			}
+
+			n, err := parseQueryName(s[i:], &step)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query %q at %d: %v", expr, i, err)
+			}
+
+			i += n
+		case s[i] == '.':
+			i++
+
+			n, err := parseQueryName(s[i:], &step)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query %q at %d: %v", expr, i, err)
+			}
+
+			i += n
+		case s[i] == '[':
+			n, err := parseQueryBracket(s[i:], &step)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query %q at %d: %v", expr, i, err)
+			}
+
+			i += n
+		default:
+			return nil, fmt.Errorf("invalid query %q at %d: unexpected %q", expr, i, s[i])
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// parseQueryName parse wildcard or key after dot
+func parseQueryName(s string, step *queryStep) (int, error) {
+	if strings.HasPrefix(s, "*") {
+		step.wildcard = true
+		return 1, nil
+	}
+
+	if len(s) == 0 {
+		return 0, fmt.Errorf("missing key")
+	}
+
+	key, n, err := parseKey(s)
+	if err != nil {
+		return 0, err
+	}
+
+	step.seg = &pathSegment{key: key}
+
+	return n, nil
+}
+
+// parseQueryBracket parse [*], [?(filter)], [n] or ['key']
+func parseQueryBracket(s string, step *queryStep) (int, error) {
+	switch {
+	case strings.HasPrefix(s, "[*]"):
+		step.wildcard = true
+		return 3, nil
+	case strings.HasPrefix(s, "[?("):
+		end := matchingParen(s, 2)
+		if end < 0 || end+1 >= len(s) || s[end+1] != ']' {
+			return 0, fmt.Errorf("unterminated filter")
+		}
+
+		filter, err := parseFilter(s[3:end])
+		if err != nil {
+			return 0, err
+		}
+
+		step.filter = filter
+		return end + 2, nil
+	default:
+		seg, n, err := parseBracket(s)
+		if err != nil {
+			return 0, err
+		}
+
+		step.seg = &seg
+		return n, nil
+	}
+}
+
+// matchingParen return position of parenthesis closing the one at open, skipping quoted strings
+func matchingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			_, n, err := parseQuoted(s[i:])
+			if err != nil {
+				return -1
+			}
+
+			i += n - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// filterExpr is filter predicate or operand evaluated against current node
+type filterExpr interface {
+	eval(node interface{}) (interface{}, bool)
+}
+
+// pathOperand select value relative to current node (@)
+type pathOperand struct {
+	segs []pathSegment
+}
+
+func (p *pathOperand) eval(node interface{}) (interface{}, bool) {
+	return getSegments(node, p.segs)
+}
+
+type literalOperand struct {
+	val interface{}
+}
+
+func (l *literalOperand) eval(node interface{}) (interface{}, bool) {
+	return l.val, true
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (n *notExpr) eval(node interface{}) (interface{}, bool) {
+	return !truthy(n.expr.eval(node)), true
+}
+
+type logicalExpr struct {
+	op          string
+	left, right filterExpr
+}
+
+func (l *logicalExpr) eval(node interface{}) (interface{}, bool) {
+	left := truthy(l.left.eval(node))
+
+	if l.op == "&&" {
+		return left && truthy(l.right.eval(node)), true
+	}
+
+	return left || truthy(l.right.eval(node)), true
+}
+
+type compareExpr struct {
+	op          string
+	left, right filterExpr
+}
+
+func (c *compareExpr) eval(node interface{}) (interface{}, bool) {
+	a, ok := c.left.eval(node)
+	if !ok {
+		return false, true
+	}
+
+	b, ok := c.right.eval(node)
+	if !ok {
+		return false, true
+	}
+
+	return compareValues(c.op, a, b), true
+}
+
+// truthy return whether filter result select the node,
+// non boolean value select the node when it exists
+func truthy(v interface{}, ok bool) bool {
+	if !ok {
+		return false
+	}
+
+	if b, isBool := v.(bool); isBool {
+		return b
+	}
+
+	return v != nil
+}
+
+// compareValues compare numbers, strings, booleans and nulls
+func compareValues(op string, a, b interface{}) bool {
+	// integers are compared exactly, large ids don't fit float64
+	if ia, ok := toInt(a); ok {
+		if ib, ok := toInt(b); ok {
+			switch op {
+			case "==":
+				return ia == ib
+			case "!=":
+				return ia != ib
+			case "<":
+				return ia < ib
+			case "<=":
+				return ia <= ib
+			case ">":
+				return ia > ib
+			case ">=":
+				return ia >= ib
+			}
+
+			return false
+		}
+	}
+
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		if !ok {
+			return op == "!="
+		}
+
+		switch op {
+		case "==":
+			return fa == fb
+		case "!=":
+			return fa != fb
+		case "<":
+			return fa < fb
+		case "<=":
+			return fa <= fb
+		case ">":
+			return fa > fb
+		case ">=":
+			return fa >= fb
+		}
+
+		return false
+	}
+
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		if !ok {
+			return op == "!="
+		}
+
+		switch op {
+		case "==":
+			return sa == sb
+		case "!=":
+			return sa != sb
+		case "<":
+			return sa < sb
+		case "<=":
+			return sa <= sb
+		case ">":
+			return sa > sb
+		case ">=":
+			return sa >= sb
+		}
+
+		return false
+	}
+
+	// booleans, nulls and composite values support equality only
+	eq := fmt.Sprint(a) == fmt.Sprint(b) && fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b)
+	switch op {
+	case "==":
+		return eq
+	case "!=":
+		return !eq
+	}
+
+	return false
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// filterParser is recursive descent parser of filter expression
+type filterParser struct {
+	s   string
+	pos int
+}
+
+func parseFilter(s string) (filterExpr, error) {
+	p := &filterParser{s: s}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q in filter", p.s[p.pos:])
+	}
+
+	return expr, nil
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// consume skip given token if it is next
+func (p *filterParser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.s[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+
+	return false
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalExpr{op: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.consume("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &logicalExpr{op: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if p.consume("!") {
+		if p.consume("=") {
+			return nil, fmt.Errorf("unexpected != in filter")
+		}
+
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &notExpr{expr: expr}, nil
+	}
+
+	if p.consume("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.consume(")") {
+			return nil, fmt.Errorf("missing closing parenthesis in filter")
+		}
+
+		return expr, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterExpr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+
+			return &compareExpr{op: op, left: left, right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseOperand() (filterExpr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("missing operand in filter")
+	}
+
+	rest := p.s[p.pos:]
+
+	switch {
+	case rest[0] == '@':
+		end := 1
+		for end < len(rest) && !strings.ContainsRune(" \t=!<>&|()", rune(rest[end])) {
+			if rest[end] == '\'' || rest[end] == '"' {
+				_, n, err := parseQuoted(rest[end:])
+				if err != nil {
+					return nil, err
+				}
+
+				end += n
+				continue
+			}
+
+			end++
+		}
+
+		segs, err := parsePath(strings.TrimPrefix(rest[1:end], "."))
+		if err != nil {
+			return nil, err
+		}
+
+		p.pos += end
+		return &pathOperand{segs: segs}, nil
+	case rest[0] == '\'' || rest[0] == '"':
+		str, n, err := parseQuoted(rest)
+		if err != nil {
+			return nil, err
+		}
+
+		p.pos += n
+		return &literalOperand{val: str}, nil
+	}
+
+	end := 0
+	for end < len(rest) && !strings.ContainsRune(" \t=!<>&|()", rune(rest[end])) {
+		end++
+	}
+
+	word := rest[:end]
+	p.pos += end
+
+	switch word {
+	case "true":
+		return &literalOperand{val: true}, nil
+	case "false":
+		return &literalOperand{val: false}, nil
+	case "null":
+		return &literalOperand{val: nil}, nil
+	}
+
+	if _, err := strconv.ParseFloat(word, 64); err != nil {
+		return nil, fmt.Errorf("invalid operand %q in filter", word)
+	}
+
+	return &literalOperand{val: json.Number(word)}, nil
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const queryTestData = `{
+	"db": {"host": "d", "port": 5432},
+	"upstreams": [
+		{"host": "a", "region": "eu", "weight": 1, "id": 9007199254740993, "tags": ["x"]},
+		{"host": "b", "region": "us", "weight": 3, "id": 9007199254740992, "backup": null},
+		{"host": "c)'", "region": "eu", "weight": 2, "disabled": true}
+	]
+}`
+
+// queryResult return matches of expression as path=json pairs
+func queryResult(t *testing.T, expr string) (string, error) {
+	t.Helper()
+
+	var tree interface{}
+	if err := decodeJSON([]byte(queryTestData), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := query(tree, expr)
+	if err != nil {
+		return "", err
+	}
+
+	res := make([]string, len(matches))
+	for i, m := range matches {
+		b, err := json.Marshal(m.val)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res[i] = formatPath(m.path) + "=" + string(b)
+	}
+
+	return strings.Join(res, " "), nil
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"$.db.host", `db.host="d"`},
+		{"$['db']['port']", `db.port=5432`},
+		{"$.upstreams[0].host", `upstreams[0].host="a"`},
+		{"$.upstreams[-1].weight", `upstreams[2].weight=2`},
+		{"$.upstreams[5].host", ``},
+		{"$.upstreams[-4].host", ``},
+		{"$.upstreams[*].host", `upstreams[0].host="a" upstreams[1].host="b" upstreams[2].host="c)'"`},
+		{"$.db.*", `db.host="d" db.port=5432`},
+		{"$.db[*]", `db.host="d" db.port=5432`},
+		{"$.missing[*]", ``},
+		{"$..host", `db.host="d" upstreams[0].host="a" upstreams[1].host="b" upstreams[2].host="c)'"`},
+		{"$..tags[0]", `upstreams[0].tags[0]="x"`},
+		{"$..[?(@.weight > 1)].host", `upstreams[1].host="b" upstreams[2].host="c)'"`},
+		{"$..[?(@ == 'x')]", `upstreams[0].tags[0]="x"`},
+
+		// logical operators
+		{"$.upstreams[?(@.region == 'eu' && @.weight > 1)].host", `upstreams[2].host="c)'"`},
+		{"$.upstreams[?(@.region == 'us' || @.weight == 1)].host", `upstreams[0].host="a" upstreams[1].host="b"`},
+		{"$.upstreams[?(!@.disabled)].host", `upstreams[0].host="a" upstreams[1].host="b"`},
+		{"$.upstreams[?(!(@.region == 'eu' && @.weight > 1))].host", `upstreams[0].host="a" upstreams[1].host="b"`},
+		{"$.upstreams[?((@.weight < 2 || @.weight > 2) && @.region != 'us')].host", `upstreams[0].host="a"`},
+		{"$.upstreams[?(@.weight >= 2 && @.weight <= 3)].host", `upstreams[1].host="b" upstreams[2].host="c)'"`},
+
+		// quoted literals
+		{`$.upstreams[?(@.host == 'c)\'')].region`, `upstreams[2].region="eu"`},
+		{`$.upstreams[?(@.host == "c)'")].region`, `upstreams[2].region="eu"`},
+		{`$.upstreams[?(@['host'] == "a")].region`, `upstreams[0].region="eu"`},
+
+		// exact integers, null and existence
+		{"$.upstreams[?(@.id == 9007199254740993)].host", `upstreams[0].host="a"`},
+		{"$.upstreams[?(@.id < 9007199254740993)].host", `upstreams[1].host="b"`},
+		{"$.upstreams[?(@.weight == 2.0)].host", `upstreams[2].host="c)'"`},
+		{"$.upstreams[?(@.backup == null)].host", `upstreams[1].host="b"`},
+		{"$.upstreams[?(@.disabled == true)].host", `upstreams[2].host="c)'"`},
+		{"$.upstreams[?(@.tags)].host", `upstreams[0].host="a"`},
+		{"$.upstreams[?(@.host == 1)].host", ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := queryResult(t, tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"db.host",
+		"$.",
+		"$..",
+		"$db",
+		"$[",
+		"$[x]",
+		"$.db[?(@.port > 1]",
+		"$.db[?(@.port > 1)",
+		"$.db[?(@.port >)]",
+		"$.db[?(@.port > 1 &&)]",
+		"$.db[?(@.port > 1 1)]",
+		"$.db[?(@.host == 'd)]",
+		"$.db[?(@.host == bogus)]",
+		"$.db[?((@.port > 1)]",
+		"$.db[?(@..port)]",
+	} {
+		if got, err := queryResult(t, expr); err == nil {
+			t.Errorf("%q: got %s, want error", expr, got)
+		}
+	}
+}
+
+func TestConfigQueryNormalizedKeys(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"Database": map[string]interface{}{
+			"Host": "a",
+			"Replicas": []interface{}{
+				map[string]interface{}{"Region": "EU", "Host": "r1"},
+				map[string]interface{}{"Region": "US", "Host": "r2"},
+			},
+		},
+	})), WithKeyNormalizer(LowerCaseKeys))
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"$.Database.Host", "database.host=a"},
+		{"$['DATABASE']['host']", "database.host=a"},
+		{"$..HOST", "database.host=a database.replicas[0].host=r1 database.replicas[1].host=r2"},
+		// keys of filter paths are normalized, literals are not
+		{"$.database.Replicas[?(@.Region == 'EU')].Host", "database.replicas[0].host=r1"},
+		{"$.database.replicas[?(@['REGION'] == 'eu')].host", ""},
+	}
+
+	for _, tt := range tests {
+		res, err := c.Query(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make([]string, len(res))
+		for i, v := range res {
+			got[i] = v.(*jsonValue).path + "=" + v.String("")
+		}
+
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestConfigQueryAlias(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"database": map[string]interface{}{
+			"host":     "a",
+			"replicas": []interface{}{map[string]interface{}{"host": "r1"}},
+		},
+	})), WithKeyNormalizer(LowerCaseKeys), Alias("db", "database", Deprecated("renamed")))
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"$.DB.host", "database.host=a"},
+		{"$.db.replicas[-1].host", "database.replicas[0].host=r1"},
+		{"$.db..host", "database.host=a database.replicas[0].host=r1"},
+		{"$.database.host", "database.host=a"},
+	}
+
+	for _, tt := range tests {
+		res, err := c.Query(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make([]string, len(res))
+		for i, v := range res {
+			got[i] = v.(*jsonValue).path + "=" + v.String("")
+		}
+
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	deps := c.Deprecations()
+	if len(deps) != 1 || deps[0].Key != "db" || deps[0].Source != "query" {
+		t.Fatalf("got deprecations %+v", deps)
+	}
+
+	// relative query of sub config is aliased by full path
+	sub := New(WithSource(Memory(map[string]interface{}{
+		"app": map[string]interface{}{"database": map[string]interface{}{"host": "a"}},
+	})), Alias("app.db", "app.database")).Sub("app")
+
+	res, err := sub.Query("$.db.host")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 1 || res[0].String("") != "a" {
+		t.Fatalf("sub: got %d matches", len(res))
+	}
+}
@@ -4,11 +4,39 @@ import (
 	"context"
 	"crypto/md5"
 	"fmt"
+	"time"
 )
 
+// Metadata describe origin of snapshot
+type Metadata struct {
+	// Source name that produced the snapshot, e.g. file:config.yaml
+	Source string
+
+	// Version of source, e.g. etcd revision, file modification time or git commit SHA
+	Version string
+
+	// Format of source stream, e.g. json, yaml
+	Format string
+
+	// Timestamp when snapshot was loaded
+	Timestamp time.Time
+}
+
 // Snapshot contains point of time loaded configuration
 type Snapshot struct {
-	Data     []byte
+	Data []byte
+
+	Metadata
+
+	// Components contains metadata of snapshots merged into this snapshot
+	Components []Metadata
+
+	// decoded json tree of data, set along with data to skip decoding it again
+	parsed interface{}
+
+	// parsed tree of data, set by TreeMerger
+	tree *node
+
 	checksum string
 }
 
@@ -45,6 +73,113 @@ type Watchable interface {
 	Watch(context.Context)
 }
 
+// Writable indicate source is able to persist changes
+type Writable interface {
+	// Write persist value of given path
+	Write(path []string, val interface{}) error
+
+	// Delete remove given path
+	Delete(path []string) error
+}
+
+// normalizedWritable is implemented by writable sources which match keys
+// of given path against existing keys by their normalized form
+type normalizedWritable interface {
+	writeNormalized(path []string, val interface{}, normalize KeyNormalizer) error
+	deleteNormalized(path []string, normalize KeyNormalizer) error
+}
+
+// HealthReporter indicate source is able to report its watcher health
+type HealthReporter interface {
+	// Health return last watch error, nil when watcher is healthy
+	Health() error
+}
+
+// Notifier indicate source is able to notify its changes
+// without waiting for next periodic read
+type Notifier interface {
+	// Notify return channel which receive on every source changes
+	Notify() <-chan struct{}
+}
+
 func checksum(b []byte) string {
 	return fmt.Sprintf("%x", md5.Sum(b))
 }
+
+// rawMapPath return path of existing keys whose normalized form matches
+// keys of normalized path, unmatched keys are kept as they are
+func rawMapPath(data map[string]interface{}, path []string, normalize KeyNormalizer) []string {
+	if normalize == nil {
+		return path
+	}
+
+	raw := make([]string, len(path))
+	for i, k := range path {
+		keys := make([]string, 0, len(data))
+		for rk := range data {
+			keys = append(keys, rk)
+		}
+
+		raw[i] = matchKey(keys, k, normalize)
+		data, _ = data[raw[i]].(map[string]interface{})
+	}
+
+	return raw
+}
+
+// matchKey return existing key whose normalized form is given normalized key,
+// key itself is returned when it exists or nothing matches
+func matchKey(keys []string, key string, normalize KeyNormalizer) string {
+	if normalize == nil {
+		return key
+	}
+
+	match, found := key, false
+	for _, k := range keys {
+		if k == key {
+			return key
+		}
+
+		// lowest matching key wins, so repeated writes hit the same key
+		if normalize(k) == key && (!found || k < match) {
+			match, found = k, true
+		}
+	}
+
+	return match
+}
+
+// setMapPath set value of nested map path, creating intermediate maps
+func setMapPath(data map[string]interface{}, path []string, val interface{}) {
+	for i, k := range path {
+		if i == len(path)-1 {
+			data[k] = val
+			return
+		}
+
+		next, ok := data[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			data[k] = next
+		}
+
+		data = next
+	}
+}
+
+// delMapPath remove nested map path
+func delMapPath(data map[string]interface{}, path []string) {
+	for i, k := range path {
+		if i == len(path)-1 {
+			delete(data, k)
+			return
+		}
+
+		next, ok := data[k].(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		data = next
+	}
+}
@@ -2,9 +2,14 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,26 +19,67 @@ import (
 	"github.com/coreos/etcd/mvcc/mvccpb"
 )
 
+// EtcdOption define etcd source options
 type EtcdOption struct {
-	Prefix   string
+	Prefix string
+
+	// Username and Password enable authentication,
+	// expired auth token is refreshed by client on next request
 	Username string
 	Password string
 
 	DialTimeout time.Duration
+
+	// CACert is CA bundle file to verify server certificate
+	CACert string
+
+	// Cert and Key are client certificate and key files for mTLS
+	Cert string
+	Key  string
+
+	// ServerName overrides server name used to verify server certificate
+	ServerName string
+
+	// InsecureSkipVerify disable server certificate verification
+	InsecureSkipVerify bool
+
+	// Config is fully built client configuration, used instead of
+	// endpoints, credentials and TLS options above
+	Config *etcd.Config
+
+	// Client is existing client to use instead of creating new one
+	Client *etcd.Client
 }
 
+// retry backoff of interrupted watch
+var (
+	etcdMinBackoff = time.Second
+	etcdMaxBackoff = time.Second * 30
+)
+
 type sourceEtcd struct {
 	host   string
 	prefix string
 	sync.RWMutex
 
-	client *etcd.Client
+	// client is created on first use, so unreachable or unauthorized
+	// cluster fail loading instead of source creation and cached
+	// snapshot can be used meanwhile
+	clientMu     sync.Mutex
+	client       *etcd.Client
+	clientConfig etcd.Config
 
 	// decoder
 	decoder Decoder
 
 	// current changeset
 	current *Snapshot
+
+	// revision of current changeset
+	rev int64
+
+	// last watch error, nil when watcher is healthy
+	watchErr error
 }
 
 func (s *sourceEtcd) Load() (*Snapshot, error) {
@@ -45,30 +91,33 @@ func (s *sourceEtcd) Load() (*Snapshot, error) {
 		return current, nil
 	}
 
-	snap, err := s.readConfig()
+	snap, rev, err := s.readConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	s.Lock()
 	s.current = snap
+	s.rev = rev
 	s.Unlock()
 
-	return s.current, nil
+	return snap, nil
 }
 
 func (s *sourceEtcd) SetDecoder(decoder Decoder) {
 	s.decoder = decoder
 }
 
-func (s *sourceEtcd) handleEvent(evs []*etcd.Event) (*Snapshot, error) {
+func (s *sourceEtcd) handleEvent(evs []*etcd.Event, rev int64) (*Snapshot, error) {
 	s.RLock()
-	data := s.current.Data
+	current := s.current
 	s.RUnlock()
 
 	var vals map[string]interface{}
-	if err := json.Unmarshal(data, &vals); err != nil {
-		return nil, err
+	if current != nil {
+		if err := decodeJSON(current.Data, &vals); err != nil {
+			return nil, err
+		}
 	}
 
 	d := makeEvMap(vals, evs, s.prefix)
@@ -80,42 +129,386 @@ func (s *sourceEtcd) handleEvent(evs []*etcd.Event) (*Snapshot, error) {
 	}
 
 	return &Snapshot{
-		Data: b,
+		Data:     b,
+		Metadata: s.metadata(rev),
 	}, nil
 }
 
+// metadata return snapshot metadata of given revision
+func (s *sourceEtcd) metadata(rev int64) Metadata {
+	return Metadata{
+		Source:    "etcd:" + s.prefix,
+		Version:   strconv.FormatInt(rev, 10),
+		Format:    "json",
+		Timestamp: time.Now(),
+	}
+}
+
+// Write put value of given path as key under prefix
+func (s *sourceEtcd) Write(path []string, val interface{}) error {
+	return s.writeNormalized(path, val, nil)
+}
+
+func (s *sourceEtcd) writeNormalized(path []string, val interface{}, normalize KeyNormalizer) error {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	return s.modify(path, normalize, func(key string, nested bool) []etcd.Op {
+		// nested keys of the path are replaced as well,
+		// value held by parent objects isn't duplicated in own key
+		ops := []etcd.Op{etcd.OpDelete(key+"/", etcd.WithPrefix())}
+		if nested {
+			return append(ops, etcd.OpDelete(key))
+		}
+
+		return append(ops, etcd.OpPut(key, string(b)))
+	}, func(data map[string]interface{}, rest []string) {
+		setMapPath(data, rest, val)
+	})
+}
+
+// Delete remove key of given path along with its nested keys
+func (s *sourceEtcd) Delete(path []string) error {
+	return s.deleteNormalized(path, nil)
+}
+
+func (s *sourceEtcd) deleteNormalized(path []string, normalize KeyNormalizer) error {
+	return s.modify(path, normalize, func(key string, nested bool) []etcd.Op {
+		return []etcd.Op{
+			etcd.OpDelete(key+"/", etcd.WithPrefix()),
+			etcd.OpDelete(key),
+		}
+	}, func(data map[string]interface{}, rest []string) {
+		delMapPath(data, rest)
+	})
+}
+
+// modify change key of path in single transaction, ops return operations
+// on the key itself while fn apply the change to json objects of parent
+// keys holding the path, which are rewritten unless modified meanwhile
+func (s *sourceEtcd) modify(path []string, normalize KeyNormalizer, ops func(key string, nested bool) []etcd.Op, fn func(data map[string]interface{}, rest []string)) error {
+	if len(path) == 0 {
+		return errors.New("empty config path")
+	}
+
+	client, err := s.connect()
+	if err != nil {
+		return err
+	}
+
+	raw, err := s.rawPath(path, normalize)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	parents, err := s.parentKeys(ctx, client, raw)
+	if err != nil {
+		return err
+	}
+
+	var cmps []etcd.Cmp
+	var then []etcd.Op
+
+	for _, p := range parents {
+		var data map[string]interface{}
+		if err := decodeJSON(p.kv.Value, &data); err != nil || data == nil {
+			continue
+		}
+
+		rest := raw[p.depth:]
+		if !hasMapPath(data, rest) {
+			continue
+		}
+
+		fn(data, rest)
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		cmps = append(cmps, etcd.Compare(etcd.ModRevision(string(p.kv.Key)), "=", p.kv.ModRevision))
+		then = append(then, etcd.OpPut(string(p.kv.Key), string(b)))
+	}
+
+	then = append(then, ops(s.pathKey(raw), len(cmps) > 0)...)
+
+	rsp, err := client.Txn(ctx).If(cmps...).Then(then...).Commit()
+	if err != nil {
+		return err
+	}
+
+	if !rsp.Succeeded {
+		return errors.New("parent key modified concurrently: " + s.pathKey(raw))
+	}
+
+	return s.reload()
+}
+
+// etcdParent is existing key of path ancestor
+type etcdParent struct {
+	kv *mvccpb.KeyValue
+
+	// number of path segments covered by the key
+	depth int
+}
+
+// parentKeys return existing keys of ancestors of raw path, nearest first,
+// prefix key itself included
+func (s *sourceEtcd) parentKeys(ctx context.Context, client *etcd.Client, raw []string) ([]etcdParent, error) {
+	var keys []string
+	var depths []int
+
+	for i := len(raw) - 1; i > 0; i-- {
+		keys = append(keys, s.pathKey(raw[:i]))
+		depths = append(depths, i)
+	}
+
+	// prefix key itself, with or without trailing slash
+	prefix := strings.TrimSuffix(s.prefix, "/")
+	for _, k := range []string{prefix, prefix + "/"} {
+		if k != "" && k != "/" {
+			keys = append(keys, k)
+			depths = append(depths, 0)
+		}
+	}
+
+	gets := make([]etcd.Op, len(keys))
+	for i, k := range keys {
+		gets[i] = etcd.OpGet(k)
+	}
+
+	rsp, err := client.Txn(ctx).Then(gets...).Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	var parents []etcdParent
+	for i, r := range rsp.Responses {
+		rr := r.GetResponseRange()
+		if rr == nil {
+			continue
+		}
+
+		for _, kv := range rr.Kvs {
+			parents = append(parents, etcdParent{kv: (*mvccpb.KeyValue)(kv), depth: depths[i]})
+		}
+	}
+
+	return parents, nil
+}
+
+// hasMapPath check nested map path exists
+func hasMapPath(data map[string]interface{}, path []string) bool {
+	for i, k := range path {
+		v, ok := data[k]
+		if !ok {
+			return false
+		}
+
+		if i == len(path)-1 {
+			return true
+		}
+
+		if data, ok = v.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+
+	return false
+}
+
+// connect return etcd client, creating it on first call
+func (s *sourceEtcd) connect() (*etcd.Client, error) {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	c, err := etcd.New(s.clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	s.client = c
+
+	return c, nil
+}
+
+// pathKey return etcd key of config path
+func (s *sourceEtcd) pathKey(path []string) string {
+	return strings.TrimSuffix(s.prefix, "/") + "/" + strings.Join(path, "/")
+}
+
+// rawPath return raw path of normalized config path, matching existing
+// keys of current changeset by their normalized form
+func (s *sourceEtcd) rawPath(path []string, normalize KeyNormalizer) ([]string, error) {
+	if normalize == nil {
+		return path, nil
+	}
+
+	s.RLock()
+	current := s.current
+	s.RUnlock()
+
+	var data map[string]interface{}
+	if current != nil {
+		if err := decodeJSON(current.Data, &data); err != nil {
+			return nil, err
+		}
+	}
+
+	return rawMapPath(data, path, normalize), nil
+}
+
+// Health return last watch error, nil when watcher is healthy
+func (s *sourceEtcd) Health() error {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.watchErr
+}
+
+// Watch keep watching prefix changes, resuming from last seen revision
+// and retrying with backoff when watch is interrupted
 func (s *sourceEtcd) Watch(ctx context.Context) {
-	ch := s.client.Watch(ctx, s.prefix, etcd.WithPrefix())
+	backoff := etcdMinBackoff
 
 	for {
+		err := s.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.Lock()
+		s.watchErr = err
+		s.Unlock()
+
+		if err == nil {
+			// clean restart, e.g. after reload of compacted revision
+			backoff = etcdMinBackoff
+			continue
+		}
+
+		log.Println("error watch etcd, retry in", backoff, "err:", err)
+
 		select {
 		case <-ctx.Done():
 			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > etcdMaxBackoff {
+			backoff = etcdMaxBackoff
+		}
+	}
+}
+
+// watch run single watch session from last seen revision,
+// returning nil when watch should be restarted immediately
+func (s *sourceEtcd) watch(ctx context.Context) error {
+	s.RLock()
+	rev := s.rev
+	s.RUnlock()
+
+	// nothing loaded yet, read full config first
+	if rev == 0 {
+		if err := s.reload(); err != nil {
+			return err
+		}
+
+		s.RLock()
+		rev = s.rev
+		s.RUnlock()
+	}
+
+	client, err := s.connect()
+	if err != nil {
+		return err
+	}
+
+	wctx, cancel := context.WithCancel(etcd.WithRequireLeader(ctx))
+	defer cancel()
+
+	ch := client.Watch(wctx, s.prefix, etcd.WithPrefix(), etcd.WithRev(rev+1))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case rsp, ok := <-ch:
 			if !ok {
-				return
+				return errors.New("watch channel closed")
+			}
+
+			if rsp.CompactRevision != 0 {
+				// missed events are compacted, fall back to full read
+				return s.reload()
 			}
 
-			if snap, err := s.handleEvent(rsp.Events); err == nil {
+			if err := rsp.Err(); err != nil {
+				return err
+			}
+
+			if len(rsp.Events) > 0 {
+				snap, err := s.handleEvent(rsp.Events, rsp.Header.Revision)
+				if err != nil {
+					return err
+				}
+
 				s.Lock()
 				s.current = snap
 				s.Unlock()
 			}
+
+			s.Lock()
+			if rsp.Header.Revision > s.rev {
+				s.rev = rsp.Header.Revision
+			}
+			s.watchErr = nil
+			s.Unlock()
 		}
 	}
 }
 
-func (s *sourceEtcd) readConfig() (*Snapshot, error) {
+// reload read full config and replace current changeset
+func (s *sourceEtcd) reload() error {
+	snap, rev, err := s.readConfig()
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.current = snap
+	s.rev = rev
+	s.Unlock()
+
+	return nil
+}
+
+func (s *sourceEtcd) readConfig() (*Snapshot, int64, error) {
+	client, err := s.connect()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
 	defer cancel()
 
-	rsp, err := s.client.Get(ctx, s.prefix, etcd.WithPrefix())
+	rsp, err := client.Get(ctx, s.prefix, etcd.WithPrefix())
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if rsp == nil || len(rsp.Kvs) == 0 {
-		return nil, fmt.Errorf("source not found: %s", s.prefix)
+		return nil, 0, fmt.Errorf("source not found: %s", s.prefix)
 	}
 
 	kvs := make([]*mvccpb.KeyValue, 0, len(rsp.Kvs))
@@ -126,51 +519,107 @@ func (s *sourceEtcd) readConfig() (*Snapshot, error) {
 	data := makeMap(kvs, s.prefix)
 	b, err := json.Marshal(data)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	return &Snapshot{
-		Data: b,
-	}, nil
+		Data:     b,
+		Metadata: s.metadata(rsp.Header.Revision),
+	}, rsp.Header.Revision, nil
 }
 
-// Etcd create etcd source loader
-func Etcd(endpoints string, vars ...EtcdOption) Loader {
-	// default config
-	var username, password, prefix string
-	dialTimeout := time.Second * 5
-
+// Etcd create etcd source loader, client connects on first load
+func Etcd(endpoints string, vars ...EtcdOption) (Loader, error) {
+	var opt EtcdOption
 	if len(vars) > 0 {
-		username = vars[0].Username
-		password = vars[0].Password
-		prefix = vars[0].Prefix
+		opt = vars[0]
+	}
 
-		if vars[0].DialTimeout > 0 {
-			dialTimeout = vars[0].DialTimeout
-		}
+	if opt.Client != nil {
+		return &sourceEtcd{
+			client: opt.Client,
+			prefix: opt.Prefix,
+		}, nil
 	}
 
+	// invalid options fail immediately while connection
+	// and authentication errors are returned by Load
+	clientConfig, err := etcdClientConfig(endpoints, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sourceEtcd{
+		clientConfig: clientConfig,
+		prefix:       opt.Prefix,
+	}, nil
+}
+
+// etcdClientConfig build client configuration from options
+func etcdClientConfig(endpoints string, opt EtcdOption) (etcd.Config, error) {
 	addrs := strings.Split(endpoints, ",")
-	if len(addrs) == 0 {
+	if len(endpoints) == 0 {
 		addrs = []string{"localhost:2379"}
 	}
 
+	if opt.Config != nil {
+		clientConfig := *opt.Config
+		if len(clientConfig.Endpoints) == 0 {
+			clientConfig.Endpoints = addrs
+		}
+
+		return clientConfig, nil
+	}
+
+	// default config
+	dialTimeout := time.Second * 5
+	if opt.DialTimeout > 0 {
+		dialTimeout = opt.DialTimeout
+	}
+
 	clientConfig := etcd.Config{
 		Endpoints:   addrs,
 		DialTimeout: dialTimeout,
-		Username:    username,
-		Password:    password,
+		Username:    opt.Username,
+		Password:    opt.Password,
 	}
 
-	c, err := etcd.New(clientConfig)
-	if err != nil {
-		log.Fatal(err)
+	if opt.CACert == "" && opt.Cert == "" && opt.Key == "" && opt.ServerName == "" && !opt.InsecureSkipVerify {
+		return clientConfig, nil
 	}
 
-	return &sourceEtcd{
-		client: c,
-		prefix: prefix,
+	tlsConfig := &tls.Config{
+		ServerName: opt.ServerName,
+		// #nosec
+		InsecureSkipVerify: opt.InsecureSkipVerify,
+	}
+
+	if opt.CACert != "" {
+		b, err := ioutil.ReadFile(opt.CACert)
+		if err != nil {
+			return clientConfig, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return clientConfig, fmt.Errorf("no certificate found in CA bundle: %s", opt.CACert)
+		}
+
+		tlsConfig.RootCAs = pool
 	}
+
+	if opt.Cert != "" || opt.Key != "" {
+		cert, err := tls.LoadX509KeyPair(opt.Cert, opt.Key)
+		if err != nil {
+			return clientConfig, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	clientConfig.TLS = tlsConfig
+
+	return clientConfig, nil
 }
 
 func makeEvMap(data map[string]interface{}, kv []*clientv3.Event, stripPrefix string) map[string]interface{} {
@@ -208,7 +657,7 @@ func update(data map[string]interface{}, v *mvccpb.KeyValue, action, stripPrefix
 	keys := strings.Split(vkey, "/")
 
 	var vals interface{}
-	if err := json.Unmarshal(v.Value, &vals); err != nil {
+	if err := decodeJSON(v.Value, &vals); err != nil {
 		vals = string(v.Value)
 	}
 
@@ -217,7 +666,12 @@ func update(data map[string]interface{}, v *mvccpb.KeyValue, action, stripPrefix
 
 		switch action {
 		case "delete":
-			data = make(map[string]interface{})
+			if key == "" {
+				// prefix key itself is deleted
+				data = make(map[string]interface{})
+			} else {
+				delete(data, key)
+			}
 		default:
 			if key == "" {
 				v, ok := vals.(map[string]interface{})
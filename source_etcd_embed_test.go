@@ -0,0 +1,253 @@
+//go:build integration
+// +build integration
+
+// Embedded etcd tests, run with
+//
+//	go test -tags integration .
+//
+// bbolt used by etcd v3.3 fails pointer checks of the race detector,
+// add -gcflags=all=-d=checkptr=0 when running them with -race
+
+package config
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/embed"
+)
+
+// startEtcd run single node etcd server in temporary directory,
+// returned func stop the server
+func startEtcd(t *testing.T) (*etcd.Client, func()) {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+
+	lc, _ := url.Parse("http://127.0.0.1:0")
+	cfg.LCUrls = []url.URL{*lc}
+	cfg.ACUrls = []url.URL{*lc}
+
+	lp, _ := url.Parse("http://127.0.0.1:0")
+	cfg.LPUrls = []url.URL{*lp}
+	cfg.APUrls = []url.URL{*lp}
+	cfg.InitialCluster = cfg.Name + "=" + lp.String()
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(time.Second * 30):
+		e.Close()
+		t.Fatal("etcd server is not ready")
+	}
+
+	c, err := etcd.New(etcd.Config{
+		Endpoints:   []string{e.Clients[0].Addr().String()},
+		DialTimeout: time.Second * 5,
+	})
+	if err != nil {
+		e.Close()
+		t.Fatal(err)
+	}
+
+	return c, func() {
+		c.Close()
+		e.Close()
+	}
+}
+
+func waitVersion(t *testing.T, src Loader, cond func(*Snapshot) bool) *Snapshot {
+	deadline := time.Now().Add(time.Second * 10)
+	for time.Now().Before(deadline) {
+		snap, err := src.Load()
+		if err == nil && cond(snap) {
+			return snap
+		}
+
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	t.Fatal("expected snapshot is not loaded")
+	return nil
+}
+
+func TestEmbeddedEtcdWatch(t *testing.T) {
+	c, stop := startEtcd(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	if _, err := c.Put(ctx, "/app/name", `"a"`); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := Etcd("", EtcdOption{Prefix: "/app", Client: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	watch := func() func() {
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			src.(Watchable).Watch(ctx)
+		}()
+
+		return func() {
+			cancel()
+			<-done
+		}
+	}
+
+	stopWatch := watch()
+
+	if _, err := c.Put(ctx, "/app/port", "8080"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitVersion(t, src, func(s *Snapshot) bool {
+		return string(s.Data) == `{"name":"a","port":8080}`
+	})
+
+	// changes made while watcher is stopped are compacted, so resumed
+	// watch fail with ErrCompacted and the source must reload
+	stopWatch()
+
+	if _, err := c.Put(ctx, "/app/name", `"b"`); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := c.Put(ctx, "/other", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Compact(ctx, last.Header.Revision, etcd.WithCompactPhysical()); err != nil {
+		t.Fatal(err)
+	}
+
+	if snap, _ := src.Load(); string(snap.Data) != `{"name":"a","port":8080}` {
+		t.Fatalf("source changed while watcher is stopped: %s", snap.Data)
+	}
+
+	stopWatch = watch()
+	defer stopWatch()
+
+	snap := waitVersion(t, src, func(s *Snapshot) bool {
+		return string(s.Data) == `{"name":"b","port":8080}`
+	})
+
+	if snap.Version != strconv.FormatInt(last.Header.Revision, 10) {
+		t.Fatalf("got version %s, want %d", snap.Version, last.Header.Revision)
+	}
+
+	if err := src.(HealthReporter).Health(); err != nil {
+		t.Fatalf("unhealthy watch: %v", err)
+	}
+
+	// watch continue after reload
+	if _, err := c.Put(ctx, "/app/port", "9090"); err != nil {
+		t.Fatal(err)
+	}
+
+	waitVersion(t, src, func(s *Snapshot) bool {
+		return string(s.Data) == `{"name":"b","port":9090}`
+	})
+}
+
+func TestEmbeddedEtcdPersistNested(t *testing.T) {
+	c, stop := startEtcd(t)
+	defer stop()
+
+	ctx := context.Background()
+
+	for k, v := range map[string]string{
+		"/app":          `{"log":{"level":"info","file":"a.log"}}`,
+		"/app/database": `{"Host":"a","port":1}`,
+		"/app/name":     `"x"`,
+	} {
+		if _, err := c.Put(ctx, k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := Etcd("", EtcdOption{Prefix: "/app", Client: c})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := New(WithSource(src), WithKeyNormalizer(LowerCaseKeys))
+
+	value := func(key string) string {
+		rsp, err := c.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(rsp.Kvs) == 0 {
+			return ""
+		}
+
+		return string(rsp.Kvs[0].Value)
+	}
+
+	// values held by json objects of parent keys are rewritten in place
+	if err := cfg.Persist("database.host", nil, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Persist("database.port", 2, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Persist("log.file", nil, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := value("/app/database"); got != `{"port":2}` {
+		t.Fatalf("got parent %s", got)
+	}
+
+	if got := value("/app"); got != `{"log":{"level":"info"}}` {
+		t.Fatalf("got prefix key %s", got)
+	}
+
+	if got := value("/app/database/port"); got != "" {
+		t.Fatalf("value duplicated in own key: %s", got)
+	}
+
+	// new values get own key
+	if err := cfg.Persist("database.user", "root", src); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := value("/app/database/user"); got != `"root"` {
+		t.Fatalf("got own key %s", got)
+	}
+
+	snap, err := src.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"database":{"port":2,"user":"root"},"log":{"level":"info"},"name":"x"}`; string(snap.Data) != want {
+		t.Fatalf("got %s, want %s", snap.Data, want)
+	}
+
+	if cfg.Get("database", "host").Exists() {
+		t.Fatal("deleted nested value exists")
+	}
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+// fakeKV serve Get of prefix from in-memory key values
+type fakeKV struct {
+	etcd.KV
+
+	sync.Mutex
+	kvs  map[string]string
+	rev  int64
+	gets int
+}
+
+func (f *fakeKV) Get(ctx context.Context, key string, opts ...etcd.OpOption) (*etcd.GetResponse, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.gets++
+
+	rsp := &etcd.GetResponse{Header: &pb.ResponseHeader{Revision: f.rev}}
+	for k, v := range f.kvs {
+		rsp.Kvs = append(rsp.Kvs, &mvccpb.KeyValue{Key: []byte(k), Value: []byte(v)})
+	}
+
+	return rsp, nil
+}
+
+func (f *fakeKV) put(key, val string, rev int64) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.kvs[key] = val
+	f.rev = rev
+}
+
+// fakeWatcher record requested revision of every watch session
+type fakeWatcher struct {
+	etcd.Watcher
+
+	sessions chan fakeSession
+}
+
+type fakeSession struct {
+	rev int64
+	ch  chan etcd.WatchResponse
+}
+
+func (f *fakeWatcher) Watch(ctx context.Context, key string, opts ...etcd.OpOption) etcd.WatchChan {
+	ch := make(chan etcd.WatchResponse)
+	f.sessions <- fakeSession{rev: etcd.OpGet(key, opts...).Rev(), ch: ch}
+
+	return ch
+}
+
+func newFakeEtcd(t *testing.T) (*sourceEtcd, *fakeKV, *fakeWatcher) {
+	kv := &fakeKV{kvs: map[string]string{"/app/name": `"a"`}, rev: 5}
+	w := &fakeWatcher{sessions: make(chan fakeSession, 8)}
+
+	src, err := Etcd("", EtcdOption{Prefix: "/app", Client: &etcd.Client{KV: kv, Watcher: w}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return src.(*sourceEtcd), kv, w
+}
+
+func nextSession(t *testing.T, w *fakeWatcher) fakeSession {
+	select {
+	case s := <-w.sessions:
+		return s
+	case <-time.After(time.Second * 5):
+		t.Fatal("watch is not restarted")
+		return fakeSession{}
+	}
+}
+
+func putEvent(key, val string, rev int64) etcd.WatchResponse {
+	return etcd.WatchResponse{
+		Header: pb.ResponseHeader{Revision: rev},
+		Events: []*etcd.Event{{
+			Type: mvccpb.PUT,
+			Kv:   &mvccpb.KeyValue{Key: []byte(key), Value: []byte(val), ModRevision: rev},
+		}},
+	}
+}
+
+func waitSnapshot(t *testing.T, src *sourceEtcd, version string) *Snapshot {
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		snap, err := src.Load()
+		if err == nil && snap.Version == version {
+			return snap
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatalf("snapshot of revision %s is not loaded", version)
+	return nil
+}
+
+func TestEtcdWatchResumeFromRevision(t *testing.T) {
+	defer func(min, max time.Duration) { etcdMinBackoff, etcdMaxBackoff = min, max }(etcdMinBackoff, etcdMaxBackoff)
+	etcdMinBackoff, etcdMaxBackoff = time.Millisecond*10, time.Millisecond*40
+
+	src, _, w := newFakeEtcd(t)
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go src.Watch(ctx)
+
+	s := nextSession(t, w)
+	if s.rev != 6 {
+		t.Fatalf("first watch from revision %d, want 6", s.rev)
+	}
+
+	s.ch <- putEvent("/app/port", "8080", 7)
+	snap := waitSnapshot(t, src, "7")
+	if string(snap.Data) != `{"name":"a","port":8080}` {
+		t.Fatalf("unexpected data %s", snap.Data)
+	}
+
+	// interrupted watch report error and resume after last seen revision
+	close(s.ch)
+
+	s = nextSession(t, w)
+	if s.rev != 8 {
+		t.Fatalf("resumed watch from revision %d, want 8", s.rev)
+	}
+
+	if src.Health() == nil {
+		t.Fatal("expected health error after interrupted watch")
+	}
+
+	s.ch <- putEvent("/app/port", "9090", 9)
+	waitSnapshot(t, src, "9")
+
+	if err := src.Health(); err != nil {
+		t.Fatalf("expected healthy watch, got %v", err)
+	}
+}
+
+func TestEtcdWatchReloadOnCompaction(t *testing.T) {
+	src, kv, w := newFakeEtcd(t)
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go src.Watch(ctx)
+
+	s := nextSession(t, w)
+
+	// events after revision 5 are compacted, config is read again
+	kv.put("/app/name", `"b"`, 20)
+	s.ch <- etcd.WatchResponse{Header: pb.ResponseHeader{Revision: 20}, CompactRevision: 15}
+
+	snap := waitSnapshot(t, src, "20")
+	if string(snap.Data) != `{"name":"b"}` {
+		t.Fatalf("unexpected data %s", snap.Data)
+	}
+
+	s = nextSession(t, w)
+	if s.rev != 21 {
+		t.Fatalf("watch after reload from revision %d, want 21", s.rev)
+	}
+}
+
+func TestEtcdWatchBackoff(t *testing.T) {
+	defer func(min, max time.Duration) { etcdMinBackoff, etcdMaxBackoff = min, max }(etcdMinBackoff, etcdMaxBackoff)
+	etcdMinBackoff, etcdMaxBackoff = time.Millisecond*20, time.Millisecond*80
+
+	src, _, w := newFakeEtcd(t)
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go src.Watch(ctx)
+
+	// every failed session double the delay up to max backoff
+	want := []time.Duration{20, 40, 80, 80}
+
+	s := nextSession(t, w)
+	for _, d := range want {
+		start := time.Now()
+		s.ch <- etcd.WatchResponse{Canceled: true, Header: pb.ResponseHeader{Revision: 5}}
+
+		s = nextSession(t, w)
+		if elapsed := time.Since(start); elapsed < d*time.Millisecond {
+			t.Fatalf("watch restarted after %v, want at least %v", elapsed, d*time.Millisecond)
+		}
+	}
+
+	if src.Health() == nil {
+		t.Fatal("expected health error of canceled watch")
+	}
+}
+
+func TestEtcdUnreachableFallbackToCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "cache.json")
+
+	// save last known good snapshot
+	New(WithSource(Memory(map[string]interface{}{"name": "cached"})), WithCache(file))
+
+	src, err := Etcd("127.0.0.1:1", EtcdOption{
+		Prefix:      "/app",
+		Username:    "user",
+		Password:    "secret",
+		DialTimeout: time.Millisecond * 100,
+	})
+	if err != nil {
+		t.Fatalf("source creation must not connect, got %v", err)
+	}
+
+	c := New(WithSource(src), WithCache(file))
+	if name := c.Get("name").String(""); name != "cached" {
+		t.Fatalf("name = %q, want cached", name)
+	}
+}
+
+func TestEtcdDeleteTopLevelKeyEvent(t *testing.T) {
+	src, kv, w := newFakeEtcd(t)
+	kv.put("/app/port", "8080", 6)
+
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go src.Watch(ctx)
+
+	s := nextSession(t, w)
+	s.ch <- etcd.WatchResponse{
+		Header: pb.ResponseHeader{Revision: 7},
+		Events: []*etcd.Event{{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("/app/port")}}},
+	}
+
+	snap := waitSnapshot(t, src, "7")
+	if string(snap.Data) != `{"name":"a"}` {
+		t.Fatalf("unexpected data %s", snap.Data)
+	}
+}
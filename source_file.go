@@ -3,11 +3,15 @@ package config
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	yaml "gopkg.in/yaml.v3"
@@ -52,6 +56,9 @@ type fileSource struct {
 	// decoder
 	decoder Decoder
 
+	// serialize modification of file
+	writeMu sync.Mutex
+
 	// current changeset
 	current *Snapshot
 }
@@ -84,6 +91,11 @@ func (s *fileSource) SetDecoder(decoder Decoder) {
 
 // readFile read configuration file and put into current snapshot
 func (s *fileSource) readFile() (*Snapshot, error) {
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return nil, err
+	}
+
 	b, err := ioutil.ReadFile(s.file)
 	if err != nil {
 		return nil, err
@@ -106,11 +118,99 @@ func (s *fileSource) readFile() (*Snapshot, error) {
 		return nil, err
 	}
 
-	snap := &Snapshot{Data: b}
+	snap := &Snapshot{
+		Data: b,
+		Metadata: Metadata{
+			Source:    "file:" + s.file,
+			Version:   info.ModTime().UTC().Format(time.RFC3339Nano),
+			Format:    s.format,
+			Timestamp: time.Now(),
+		},
+	}
 
 	return snap, nil
 }
 
+// Write rewrite file with value of given path, preserving its format
+func (s *fileSource) Write(path []string, val interface{}) error {
+	return s.writeNormalized(path, val, nil)
+}
+
+// Delete rewrite file without given path, preserving its format
+func (s *fileSource) Delete(path []string) error {
+	return s.deleteNormalized(path, nil)
+}
+
+func (s *fileSource) writeNormalized(path []string, val interface{}, normalize KeyNormalizer) error {
+	return s.modify(fileEdit{path: path, val: val, normalize: normalize})
+}
+
+func (s *fileSource) deleteNormalized(path []string, normalize KeyNormalizer) error {
+	return s.modify(fileEdit{path: path, del: true, normalize: normalize})
+}
+
+// modify apply edit to file content and replace the file atomically,
+// comments and key order of the file are kept
+func (s *fileSource) modify(e fileEdit) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	// encoded file must be encoded back
+	var codec Codec
+	if s.decoder != nil {
+		c, ok := s.decoder.(Codec)
+		if !ok {
+			return errors.New("file with decoder is writable only when decoder is Codec: " + s.file)
+		}
+
+		codec = c
+	}
+
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(s.file)
+	if err != nil {
+		return err
+	}
+
+	if codec != nil {
+		b = codec.Decode(b)
+	}
+
+	switch s.format {
+	case "yaml":
+		b, err = editYAML(b, e)
+	default:
+		b, err = editJSON(b, e)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.file, err)
+	}
+
+	if codec != nil {
+		b = codec.Encode(b)
+	}
+
+	if err := writeFileAtomic(s.file, b, info.Mode()); err != nil {
+		return err
+	}
+
+	snap, err := s.readFile()
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.current = snap
+	s.Unlock()
+
+	return nil
+}
+
 func (s *fileSource) Watch(ctx context.Context) {
 	if !s.watch {
 		return
@@ -118,33 +218,52 @@ func (s *fileSource) Watch(ctx context.Context) {
 
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Fatal(err)
+		log.Println("error create file watcher, err:", err)
+		return
+	}
+
+	defer watcher.Close()
+
+	// file replaced by rename, by Persist or editors, is a new file
+	// which is seen only by watching its directory
+	if err := watcher.Add(filepath.Dir(s.file)); err != nil {
+		log.Println("error watch config file, err:", err)
+		return
 	}
 
-	watcher.Add(s.file)
+	name := filepath.Clean(s.file)
 
 	for {
 		select {
 		case <-ctx.Done():
-			watcher.Close()
 			return
 		case event, ok := <-watcher.Events:
 			if !ok {
-				break
+				return
 			}
 
-			if event.Op&fsnotify.Write == fsnotify.Write {
-				snap, err := s.readFile()
-				if err != nil {
-					log.Fatal(err)
-				}
-
-				s.Lock()
-				if s.current.Checksum() != snap.Checksum() {
-					s.current = snap
-				}
-				s.Unlock()
+			if filepath.Clean(event.Name) != name || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
 			}
+
+			// keep current snapshot on invalid content
+			snap, err := s.readFile()
+			if err != nil {
+				log.Println("error read config file, err:", err)
+				continue
+			}
+
+			s.Lock()
+			if s.current == nil || s.current.Checksum() != snap.Checksum() {
+				s.current = snap
+			}
+			s.Unlock()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			log.Println("error watch config file, err:", err)
 		}
 	}
 }
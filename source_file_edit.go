@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// fileEdit is change of single path applied to file content
+type fileEdit struct {
+	path      []string
+	val       interface{}
+	del       bool
+	normalize KeyNormalizer
+}
+
+// editJSON apply edit to json content keeping key order and indentation
+func editJSON(b []byte, e fileEdit) ([]byte, error) {
+	root, err := parseNode(b, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.kind != objectNode {
+		return nil, errors.New("config must be an object")
+	}
+
+	// resolve raw keys along existing objects
+	segs := make([]pathSegment, len(e.path))
+	for i, n := 0, root; i < len(e.path); i++ {
+		var keys []string
+		if n != nil && n.kind == objectNode {
+			keys = n.keys
+		}
+
+		segs[i] = pathSegment{key: matchKey(keys, e.path[i], e.normalize)}
+
+		if n != nil {
+			n = n.fields[segs[i].key]
+		}
+	}
+
+	if e.del {
+		root = root.del(segs)
+	} else {
+		val, err := nodeOf(e.val, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		root = root.set(segs, val)
+	}
+
+	out, err := root.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, out, "", fileIndent(b, "    ")); err != nil {
+		return nil, err
+	}
+
+	buf.WriteByte('\n')
+
+	return buf.Bytes(), nil
+}
+
+// editYAML apply edit to yaml document keeping comments and key order
+func editYAML(b []byte, e fileEdit) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	// empty document
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("config must be an object")
+	}
+
+	if e.del {
+		delYAMLPath(doc.Content[0], e.path, e.normalize)
+	} else {
+		out, err := yaml.Marshal(plainTree(e.val))
+		if err != nil {
+			return nil, err
+		}
+
+		var val yaml.Node
+		if err := yaml.Unmarshal(out, &val); err != nil {
+			return nil, err
+		}
+
+		setYAMLPath(doc.Content[0], e.path, val.Content[0], e.normalize)
+	}
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(len(fileIndent(b, "    ")))
+
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// setYAMLPath set value of path in mapping node, creating missing mappings,
+// comments of replaced value are kept
+func setYAMLPath(m *yaml.Node, path []string, val *yaml.Node, normalize KeyNormalizer) {
+	for i, k := range path {
+		k = matchKey(yamlKeys(m), k, normalize)
+
+		idx := yamlValueIndex(m, k)
+		if idx < 0 {
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, nil)
+			idx = len(m.Content) - 1
+		}
+
+		if i == len(path)-1 {
+			if prev := m.Content[idx]; prev != nil {
+				val.HeadComment, val.LineComment, val.FootComment = prev.HeadComment, prev.LineComment, prev.FootComment
+			}
+
+			m.Content[idx] = val
+			return
+		}
+
+		if m.Content[idx] == nil || m.Content[idx].Kind != yaml.MappingNode {
+			m.Content[idx] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+
+		m = m.Content[idx]
+	}
+}
+
+// delYAMLPath remove key of path from mapping node
+func delYAMLPath(m *yaml.Node, path []string, normalize KeyNormalizer) {
+	for i, k := range path {
+		idx := yamlValueIndex(m, matchKey(yamlKeys(m), k, normalize))
+		if idx < 0 {
+			return
+		}
+
+		if i == len(path)-1 {
+			m.Content = append(m.Content[:idx-1], m.Content[idx+1:]...)
+			return
+		}
+
+		if m = m.Content[idx]; m.Kind != yaml.MappingNode {
+			return
+		}
+	}
+}
+
+// yamlKeys return keys of mapping node
+func yamlKeys(m *yaml.Node) []string {
+	keys := make([]string, 0, len(m.Content)/2)
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		keys = append(keys, m.Content[i].Value)
+	}
+
+	return keys
+}
+
+// yamlValueIndex return index of value of key in mapping node, -1 if not found
+func yamlValueIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i + 1
+		}
+	}
+
+	return -1
+}
+
+// fileIndent return indentation of first indented line of content
+func fileIndent(b []byte, def string) string {
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) == 0 || len(trimmed) == len(line) {
+			continue
+		}
+
+		return string(line[:len(line)-len(trimmed)])
+	}
+
+	return def
+}
+
+// writeFileAtomic write file through temporary file in the same directory
+// renamed over it, so readers never see partially written content
+func writeFileAtomic(file string, b []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func readTestFile(t *testing.T, file string) string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestFilePersistKeepYAMLFormat(t *testing.T) {
+	file := writeTestFile(t, "config.yaml", `# service config
+
+name: app
+Database:
+  # primary host
+  Host: a # inline
+  Port: 1
+`)
+
+	src := File(file)
+	c := New(WithSource(src), WithKeyNormalizer(LowerCaseKeys))
+
+	if err := c.Persist("database.host", "b", src); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Persist("database.user", "root", src); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Persist("name", nil, src); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# service config
+
+Database:
+  # primary host
+  Host: b # inline
+  Port: 1
+  user: root
+`
+	if got := readTestFile(t, file); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFilePersistKeepJSONOrder(t *testing.T) {
+	file := writeTestFile(t, "config.json", "{\n  \"z\": 1,\n  \"a\": {\n    \"y\": true,\n    \"b\": [1, 2]\n  }\n}\n")
+
+	src := File(file)
+	c := New(WithSource(src))
+
+	if err := c.Persist("a.y", false, src); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Persist("m", map[string]interface{}{"k": "v"}, src); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "{\n  \"z\": 1,\n  \"a\": {\n    \"y\": false,\n    \"b\": [\n      1,\n      2\n    ]\n  },\n  \"m\": {\n    \"k\": \"v\"\n  }\n}\n"
+	if got := readTestFile(t, file); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFilePersistRejectNonObject(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.json": `[1]`,
+		"config.yaml": "- 1\n",
+	} {
+		file := writeTestFile(t, name, content)
+
+		if err := File(file).(Writable).Write([]string{"a"}, 1); err == nil {
+			t.Fatalf("%s: written into non-object root", name)
+		}
+
+		if got := readTestFile(t, file); got != content {
+			t.Fatalf("%s: file modified: %s", name, got)
+		}
+	}
+}
+
+func TestFileWatchPersist(t *testing.T) {
+	file := writeTestFile(t, "config.yaml", "name: a\n")
+
+	src := File(file, true)
+	if _, err := src.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go src.(Watchable).Watch(ctx)
+
+	// let watcher start before file is replaced
+	time.Sleep(time.Millisecond * 50)
+
+	// invalid content keeps current snapshot instead of crashing
+	if err := ioutil.WriteFile(file, []byte("name: [a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	if err := ioutil.WriteFile(file, []byte("name: a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// each persist replace the file, watcher must keep following it
+	for _, name := range []string{"b", "c"} {
+		if err := src.(Writable).Write([]string{"name"}, name); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(file, []byte("name: "+name+"-external\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		want := `{"name":"` + name + `-external"}`
+
+		deadline := time.Now().Add(time.Second * 5)
+		for {
+			snap, err := src.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(snap.Data) == want {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("got %s, want %s", snap.Data, want)
+			}
+
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+}
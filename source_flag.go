@@ -4,77 +4,147 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
-	"log"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/imdario/mergo"
 )
 
+// FlagOption define command line source options
+type FlagOption struct {
+	// IncludeDefaults include flags which are not set by user
+	// using their default value
+	IncludeDefaults bool
+}
+
 type flagSource struct {
 	sync.RWMutex
 
+	// flag set reader, read once after flags parsed
+	read func() (*Snapshot, error)
+
 	// current changeset
 	current *Snapshot
 }
 
 func (s *flagSource) Load() (*Snapshot, error) {
 	s.RLock()
-	defer s.RUnlock()
+	current := s.current
+	s.RUnlock()
+
+	if current != nil {
+		return current, nil
+	}
 
-	return s.current, nil
+	snap, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	s.current = snap
+	s.Unlock()
+
+	return snap, nil
 }
 
 func (s *flagSource) SetDecoder(decoder Decoder) {
 	// cli arguments don't support decoding
 }
 
-func (s *flagSource) readFlags() (*Snapshot, error) {
-	if !flag.Parsed() {
-		return nil, errors.New("flag.Parse() must be called before")
+// readFlagSet read flags of given flag set using their typed value
+func readFlagSet(fs *flag.FlagSet, includeDefaults bool) (*Snapshot, error) {
+	if !fs.Parsed() {
+		return nil, errors.New("flag set must be parsed before loaded: " + fs.Name())
 	}
 
-	var d map[string]interface{}
+	d := make(map[string]interface{})
+
+	var err error
 	visitFn := func(f *flag.Flag) {
-		n := strings.ToLower(f.Name)
-		keys := strings.FieldsFunc(n, split)
-		reverse(keys)
-
-		tmp := make(map[string]interface{})
-		for i, k := range keys {
-			if i == 0 {
-				tmp[k] = f.Value
-				continue
-			}
-
-			tmp = map[string]interface{}{k: tmp}
+		if err == nil {
+			err = setFlag(d, f.Name, flagValue(f.Value))
 		}
+	}
 
-		mergo.Map(&d, tmp) // need to sort error handling
-		return
+	if includeDefaults {
+		fs.VisitAll(visitFn)
+	} else {
+		fs.Visit(visitFn)
 	}
 
-	flag.VisitAll(visitFn)
+	if err != nil {
+		return nil, err
+	}
 
 	b, err := json.Marshal(d)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Snapshot{Data: b}, nil
+	return &Snapshot{
+		Data: b,
+		Metadata: Metadata{
+			Source:    "flag:" + fs.Name(),
+			Format:    "flag",
+			Timestamp: time.Now(),
+		},
+	}, nil
 }
 
-// Cli create config source from command line arguments
-func Cli() Loader {
+// flagValue return typed value of flag
+func flagValue(v flag.Value) interface{} {
+	getter, ok := v.(flag.Getter)
+	if !ok {
+		return v.String()
+	}
 
-	s := &flagSource{}
+	switch val := getter.Get().(type) {
+	case time.Duration:
+		// keep duration parsable by Value.Duration
+		return val.String()
+	default:
+		return val
+	}
+}
 
-	snap, err := s.readFlags()
-	if err != nil {
-		log.Fatal(err)
+// setFlag put flag value into nested map using its dotted name
+func setFlag(d map[string]interface{}, name string, val interface{}) error {
+	keys := strings.FieldsFunc(strings.ToLower(name), split)
+	reverse(keys)
+
+	tmp := make(map[string]interface{})
+	for i, k := range keys {
+		if i == 0 {
+			tmp[k] = val
+			continue
+		}
+
+		tmp = map[string]interface{}{k: tmp}
 	}
+
+	return mergo.Map(&d, tmp, mergo.WithOverride)
+}
+
+// Cli create config source from command line arguments of flag.CommandLine,
+// only flags set by user are loaded unless IncludeDefaults is set
+func Cli(vars ...FlagOption) Loader {
+	return CliFlagSet(flag.CommandLine, vars...)
+}
+
+// CliFlagSet create config source from flags of given flag set,
+// only flags set by user are loaded unless IncludeDefaults is set
+func CliFlagSet(fs *flag.FlagSet, vars ...FlagOption) Loader {
+	var includeDefaults bool
+	if len(vars) > 0 {
+		includeDefaults = vars[0].IncludeDefaults
+	}
+
 	return &flagSource{
-		current: snap,
+		read: func() (*Snapshot, error) {
+			return readFlagSet(fs, includeDefaults)
+		},
 	}
 }
 
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func newTestFlagSet(t *testing.T, args ...string) *flag.FlagSet {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.Int("nested.props.really", 55, "")
+	fs.Bool("debug", false, "")
+	fs.Duration("timeout", time.Second, "")
+	fs.String("name", "default", "")
+
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return fs
+}
+
+func TestCliFlagSetKeepLowerLayer(t *testing.T) {
+	fs := newTestFlagSet(t, "-name=cli")
+
+	c := New(
+		WithSource(Memory(map[string]interface{}{
+			"nested": map[string]interface{}{"props": map[string]interface{}{"really": 7}},
+			"name":   "memory",
+		})),
+		WithSource(CliFlagSet(fs)),
+	)
+
+	// unset flag default doesn't override lower layer
+	if got := c.Get("nested.props.really").Int(0); got != 7 {
+		t.Fatalf("got really %d, want 7", got)
+	}
+
+	if got := c.Get("name").String(""); got != "cli" {
+		t.Fatalf("got name %q, want cli", got)
+	}
+}
+
+func TestCliFlagSetIncludeDefaults(t *testing.T) {
+	fs := newTestFlagSet(t, "-debug", "-timeout=1m30s")
+
+	snap, err := CliFlagSet(fs, FlagOption{IncludeDefaults: true}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// values keep their json type
+	want := `{"debug":true,"name":"default","nested":{"props":{"really":55}},"timeout":"1m30s"}`
+	if string(snap.Data) != want {
+		t.Fatalf("got  %s\nwant %s", snap.Data, want)
+	}
+
+	if snap.Source != "flag:app" {
+		t.Fatalf("got source %s", snap.Source)
+	}
+
+	c := New(
+		WithSource(Memory(map[string]interface{}{"nested": map[string]interface{}{"props": map[string]interface{}{"really": 7}}})),
+		WithSource(CliFlagSet(fs, FlagOption{IncludeDefaults: true})),
+	)
+
+	if got := c.Get("nested.props.really").Int(0); got != 55 {
+		t.Fatalf("got really %d, want default 55", got)
+	}
+
+	if got := c.Get("timeout").Duration(0); got != time.Second*90 {
+		t.Fatalf("got timeout %v", got)
+	}
+
+	if got, err := c.Get("debug").BoolE(); err != nil || !got {
+		t.Fatalf("got debug %v, %v", got, err)
+	}
+}
+
+func TestCliFlagSetNotParsed(t *testing.T) {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+
+	if _, err := CliFlagSet(fs).Load(); err == nil {
+		t.Fatal("unparsed flag set loaded")
+	}
+}
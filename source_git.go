@@ -0,0 +1,258 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imdario/mergo"
+)
+
+// GitOption define git source options
+type GitOption struct {
+	// Ref is branch, tag or commit to read, default to HEAD
+	Ref string
+
+	// Path is glob pattern of config files relative to repository root,
+	// matched files are merged in lexical order, default to *.json
+	Path string
+
+	// PollInterval is how often watcher check the ref for new commits
+	PollInterval time.Duration
+}
+
+type gitSource struct {
+	repo         string
+	ref          string
+	pattern      string
+	pollInterval time.Duration
+	sync.RWMutex
+
+	// decoder
+	decoder Decoder
+
+	// current changeset
+	current *Snapshot
+}
+
+// Load read initial change set
+func (s *gitSource) Load() (*Snapshot, error) {
+	s.RLock()
+	current := s.current
+	s.RUnlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	sha, err := s.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := s.readCommit(sha)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	s.current = snap
+	s.Unlock()
+
+	return snap, nil
+}
+
+func (s *gitSource) SetDecoder(decoder Decoder) {
+	s.decoder = decoder
+}
+
+func (s *gitSource) Watch(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sha, err := s.resolve()
+			if err != nil {
+				log.Println("error resolve git ref, err:", err)
+				continue
+			}
+
+			s.RLock()
+			current := s.current
+			s.RUnlock()
+
+			if current != nil && current.Version == sha {
+				continue
+			}
+
+			snap, err := s.readCommit(sha)
+			if err != nil {
+				log.Println("error read git commit, err:", err)
+				continue
+			}
+
+			s.Lock()
+			s.current = snap
+			s.Unlock()
+		}
+	}
+}
+
+// resolve return commit SHA the ref is currently pointing to
+func (s *gitSource) resolve() (string, error) {
+	b, err := s.git("rev-parse", "--verify", s.ref+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// readCommit read and merge matched files at given commit
+func (s *gitSource) readCommit(sha string) (*Snapshot, error) {
+	b, err := s.git("ls-tree", "-r", "-z", "--name-only", sha)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0)
+	formats := make([]string, 0)
+	for _, name := range strings.Split(string(b), "\x00") {
+		if name == "" {
+			continue
+		}
+
+		ok, err := path.Match(s.pattern, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			files = append(files, name)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("source not found: %s in %s@%s", s.pattern, s.repo, sha)
+	}
+
+	sort.Strings(files)
+
+	var merged map[string]interface{}
+	for _, name := range files {
+		b, err := s.git("show", sha+":"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		// if decoder assigned, then decode stream before transforming
+		if s.decoder != nil {
+			b = s.decoder.Decode(b)
+		}
+
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+		if !containsString(formats, ext) {
+			formats = append(formats, ext)
+		}
+
+		transformer, ok := fileTransformers[ext]
+		if !ok {
+			// fallback to json
+			transformer = &jsonFileTransformer{}
+		}
+
+		b, err = transformer.Transform(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+
+		var data map[string]interface{}
+		if err := decodeJSON(b, &data); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+
+		if err := mergo.Map(&merged, data, mergo.WithOverride); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err = json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Data: b,
+		Metadata: Metadata{
+			Source:    "git:" + s.repo + "@" + s.ref,
+			Version:   sha,
+			Format:    strings.Join(formats, ","),
+			Timestamp: time.Now(),
+		},
+	}, nil
+}
+
+func (s *gitSource) git(args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", s.repo}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// Git create config source from files of local or bare git repository
+func Git(repo string, vars ...GitOption) Loader {
+	// default config
+	ref := "HEAD"
+	pattern := "*.json"
+	pollInterval := time.Second * 10
+
+	if len(vars) > 0 {
+		if vars[0].Ref != "" {
+			ref = vars[0].Ref
+		}
+
+		if vars[0].Path != "" {
+			pattern = vars[0].Path
+		}
+
+		if vars[0].PollInterval > 0 {
+			pollInterval = vars[0].PollInterval
+		}
+	}
+
+	return &gitSource{
+		repo:         repo,
+		ref:          ref,
+		pattern:      pattern,
+		pollInterval: pollInterval,
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitRun run git command in dir and return its trimmed output
+func gitRun(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@localhost"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// gitCommit write files into repository and commit them, return commit SHA
+func gitCommit(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "-m", "update")
+
+	return gitRun(t, dir, "rev-parse", "HEAD")
+}
+
+func newGitRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	gitRun(t, dir, "init", "-q")
+
+	return dir
+}
+
+func TestGitLoad(t *testing.T) {
+	dir := newGitRepo(t)
+	sha := gitCommit(t, dir, map[string]string{
+		"a.json":     `{"name":"a","db":{"host":"localhost"}}`,
+		"b.yaml":     "db:\n  port: 5432\n",
+		"readme.txt": "not config",
+	})
+
+	snap, err := Git(dir, GitOption{Path: "*.[jy]*"}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"db":{"host":"localhost","port":5432},"name":"a"}`; string(snap.Data) != want {
+		t.Fatalf("got %s, want %s", snap.Data, want)
+	}
+
+	if snap.Version != sha {
+		t.Fatalf("got version %s, want %s", snap.Version, sha)
+	}
+
+	if snap.Format != "json,yaml" {
+		t.Fatalf("got format %s", snap.Format)
+	}
+
+	if snap.Source != "git:"+dir+"@HEAD" {
+		t.Fatalf("got source %s", snap.Source)
+	}
+}
+
+func TestGitBareRepository(t *testing.T) {
+	dir := newGitRepo(t)
+	sha := gitCommit(t, dir, map[string]string{"config.json": `{"a":1}`})
+
+	bare := filepath.Join(t.TempDir(), "config.git")
+	gitRun(t, dir, "clone", "-q", "--bare", dir, bare)
+
+	snap, err := Git(bare).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(snap.Data) != `{"a":1}` || snap.Version != sha {
+		t.Fatalf("got %s at %s", snap.Data, snap.Version)
+	}
+}
+
+func TestGitPathGlobInSubdirectory(t *testing.T) {
+	dir := newGitRepo(t)
+	gitCommit(t, dir, map[string]string{
+		"root.json":        `{"root":true}`,
+		"conf/a.json":      `{"a":1,"b":1}`,
+		"conf/b.json":      `{"b":2}`,
+		"conf/nested/c.js": `{"c":3}`,
+	})
+
+	snap, err := Git(dir, GitOption{Path: "conf/*.json"}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files are merged in lexical order
+	if want := `{"a":1,"b":2}`; string(snap.Data) != want {
+		t.Fatalf("got %s, want %s", snap.Data, want)
+	}
+
+	if _, err := Git(dir, GitOption{Path: "missing/*.json"}).Load(); err == nil {
+		t.Fatal("missing files loaded")
+	}
+}
+
+func TestGitWatchNewCommit(t *testing.T) {
+	dir := newGitRepo(t)
+	first := gitCommit(t, dir, map[string]string{"config.json": `{"v":1}`})
+
+	src := Git(dir, GitOption{PollInterval: time.Millisecond * 10})
+
+	snap, err := src.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snap.Version != first {
+		t.Fatalf("got version %s, want %s", snap.Version, first)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go src.(Watchable).Watch(ctx)
+
+	second := gitCommit(t, dir, map[string]string{"config.json": `{"v":2}`})
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		snap, err := src.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if snap.Version == second {
+			if string(snap.Data) != `{"v":2}` {
+				t.Fatalf("got %s", snap.Data)
+			}
+
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("watcher didn't pick up commit %s, current %s", second, snap.Version)
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
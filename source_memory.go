@@ -0,0 +1,225 @@
+package config
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryLoader is in-memory config source which values
+// can be replaced at runtime
+type MemoryLoader interface {
+	Loader
+	Notifier
+	Writable
+
+	// Update replace source values and notify watchers
+	Update(data map[string]interface{}) error
+}
+
+type memorySource struct {
+	sync.RWMutex
+
+	// notify channel
+	notify chan struct{}
+
+	// serialize modification of current values
+	writeMu sync.Mutex
+
+	// error of last marshalled values
+	err error
+
+	// number of updates
+	version int
+
+	// current changeset
+	current *Snapshot
+}
+
+func (s *memorySource) Load() (*Snapshot, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.current, s.err
+}
+
+func (s *memorySource) SetDecoder(decoder Decoder) {
+	// in-memory values don't support decoding
+}
+
+func (s *memorySource) Notify() <-chan struct{} {
+	return s.notify
+}
+
+func (s *memorySource) Write(path []string, val interface{}) error {
+	return s.writeNormalized(path, val, nil)
+}
+
+func (s *memorySource) Delete(path []string) error {
+	return s.deleteNormalized(path, nil)
+}
+
+func (s *memorySource) writeNormalized(path []string, val interface{}, normalize KeyNormalizer) error {
+	return s.modify(func(data map[string]interface{}) {
+		setMapPath(data, rawMapPath(data, path, normalize), val)
+	})
+}
+
+func (s *memorySource) deleteNormalized(path []string, normalize KeyNormalizer) error {
+	return s.modify(func(data map[string]interface{}) {
+		delMapPath(data, rawMapPath(data, path, normalize))
+	})
+}
+
+// modify apply fn to copy of current values and update the source
+func (s *memorySource) modify(fn func(map[string]interface{})) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.RLock()
+	current := s.current
+	s.RUnlock()
+
+	data := make(map[string]interface{})
+	if current != nil {
+		if err := decodeJSON(current.Data, &data); err != nil {
+			return err
+		}
+	}
+
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	fn(data)
+
+	return s.update(data)
+}
+
+func (s *memorySource) Update(data map[string]interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.update(data)
+}
+
+// update replace current values, caller must hold writeMu
+func (s *memorySource) update(data map[string]interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.version++
+	s.current = s.snapshot(b)
+	s.err = nil
+	s.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+		// pending notification
+	}
+
+	return nil
+}
+
+// Memory create config source from in-memory values
+func Memory(data map[string]interface{}) MemoryLoader {
+	s := &memorySource{
+		notify: make(chan struct{}, 1),
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		s.err = err
+		return s
+	}
+
+	s.current = s.snapshot(b)
+
+	return s
+}
+
+func (s *memorySource) snapshot(b []byte) *Snapshot {
+	return &Snapshot{
+		Data: b,
+		Metadata: Metadata{
+			Source:    "memory",
+			Version:   strconv.Itoa(s.version),
+			Timestamp: time.Now(),
+		},
+	}
+}
+
+type bytesSource struct {
+	data   []byte
+	format string
+	sync.RWMutex
+
+	// decoder
+	decoder Decoder
+
+	// current changeset
+	current *Snapshot
+}
+
+func (s *bytesSource) Load() (*Snapshot, error) {
+	s.RLock()
+	current := s.current
+	s.RUnlock()
+
+	if current != nil {
+		return current, nil
+	}
+
+	b := s.data
+
+	// if decoder assigned, then decode stream before transforming
+	if s.decoder != nil {
+		b = s.decoder.Decode(b)
+	}
+
+	// transform based on format
+	transformer, ok := fileTransformers[s.format]
+	if !ok {
+		// fallback to json
+		transformer = &jsonFileTransformer{}
+	}
+
+	b, err := transformer.Transform(b)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		Data: b,
+		Metadata: Metadata{
+			Source:    "bytes",
+			Version:   checksum(s.data),
+			Format:    s.format,
+			Timestamp: time.Now(),
+		},
+	}
+
+	s.Lock()
+	s.current = snap
+	s.Unlock()
+
+	return snap, nil
+}
+
+func (s *bytesSource) SetDecoder(decoder Decoder) {
+	s.decoder = decoder
+}
+
+// Bytes create config source from embedded stream of given format (json, yaml)
+func Bytes(data []byte, format string) Loader {
+	return &bytesSource{
+		data:   data,
+		format: strings.ToLower(strings.TrimPrefix(format, ".")),
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryUpdateNotify(t *testing.T) {
+	src := Memory(map[string]interface{}{"a": 1})
+
+	if err := src.Update(map[string]interface{}{"a": 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// pending notification is not duplicated
+	if err := src.Update(map[string]interface{}{"a": 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-src.Notify():
+	case <-time.After(time.Second):
+		t.Fatal("update didn't notify")
+	}
+
+	select {
+	case <-src.Notify():
+		t.Fatal("pending notification duplicated")
+	default:
+	}
+
+	snap, err := src.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(snap.Data) != `{"a":3}` || snap.Version != "2" {
+		t.Fatalf("got %s at version %s", snap.Data, snap.Version)
+	}
+}
+
+// update must not be applied between read and update of write
+func TestMemoryUpdateWaitWrite(t *testing.T) {
+	src := Memory(map[string]interface{}{"a": 1})
+	s := src.(*memorySource)
+
+	// write in progress
+	s.writeMu.Lock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		src.Update(map[string]interface{}{"a": 2})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("update applied during write")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	s.writeMu.Unlock()
+	<-done
+
+	if snap, _ := src.Load(); string(snap.Data) != `{"a":2}` {
+		t.Fatalf("got %s", snap.Data)
+	}
+
+	if err := src.Write([]string{"b"}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if snap, _ := src.Load(); string(snap.Data) != `{"a":2,"b":true}` {
+		t.Fatalf("got %s", snap.Data)
+	}
+}
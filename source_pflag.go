@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// readPFlagSet read flags of given pflag set using their typed value
+func readPFlagSet(fs *pflag.FlagSet, includeDefaults bool) (*Snapshot, error) {
+	if !fs.Parsed() {
+		return nil, errors.New("flag set must be parsed before loaded: " + fs.Name())
+	}
+
+	d := make(map[string]interface{})
+
+	var err error
+	visitFn := func(f *pflag.Flag) {
+		if err == nil {
+			err = setFlag(d, f.Name, pflagValue(f.Value))
+		}
+	}
+
+	if includeDefaults {
+		fs.VisitAll(visitFn)
+	} else {
+		fs.Visit(visitFn)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Data: b,
+		Metadata: Metadata{
+			Source:    "pflag:" + fs.Name(),
+			Format:    "flag",
+			Timestamp: time.Now(),
+		},
+	}, nil
+}
+
+// pflagValue return typed value of flag based on its declared type
+func pflagValue(v pflag.Value) interface{} {
+	typ := v.Type()
+
+	if sv, ok := v.(pflag.SliceValue); ok {
+		elemType := strings.TrimSuffix(typ, "Slice")
+		items := sv.GetSlice()
+
+		res := make([]interface{}, len(items))
+		for i, item := range items {
+			res[i] = parsePFlag(elemType, item)
+		}
+
+		return res
+	}
+
+	return parsePFlag(typ, v.String())
+}
+
+func parsePFlag(typ, s string) interface{} {
+	switch typ {
+	case "bool":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "int", "int8", "int16", "int32", "int64", "count":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return u
+		}
+	case "float32", "float64":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+
+	return s
+}
+
+// CliPFlagSet create config source from flags of given pflag set (cobra command flags),
+// only flags set by user are loaded unless IncludeDefaults is set
+func CliPFlagSet(fs *pflag.FlagSet, vars ...FlagOption) Loader {
+	var includeDefaults bool
+	if len(vars) > 0 {
+		includeDefaults = vars[0].IncludeDefaults
+	}
+
+	return &flagSource{
+		read: func() (*Snapshot, error) {
+			return readPFlagSet(fs, includeDefaults)
+		},
+	}
+}
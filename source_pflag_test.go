@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestCliPFlagSetTypes(t *testing.T) {
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	fs.Int("port", 80, "")
+	fs.Uint("workers", 1, "")
+	fs.Float64("ratio", 0.5, "")
+	fs.Bool("debug", false, "")
+	fs.Duration("timeout", time.Second, "")
+	fs.IntSlice("ids", nil, "")
+	fs.Float64Slice("weights", nil, "")
+	fs.StringSlice("tags", nil, "")
+	fs.StringArray("names", nil, "")
+	fs.CountP("verbose", "v", "")
+	fs.String("db.host", "localhost", "")
+	fs.String("unset", "default", "")
+
+	err := fs.Parse([]string{
+		"--port=8080", "--workers=4", "--ratio=1.5", "--debug",
+		"--timeout=1m30s", "--ids=1,2", "--weights=0.5,2",
+		"--tags=a,b", "--names=x,y", "--names=z", "-vvv",
+		"--db.host=db",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := CliPFlagSet(fs).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"db":{"host":"db"},"debug":true,"ids":[1,2],"names":["x,y","z"],` +
+		`"port":8080,"ratio":1.5,"tags":["a","b"],"timeout":"1m30s","verbose":3,` +
+		`"weights":[0.5,2],"workers":4}`
+	if string(snap.Data) != want {
+		t.Fatalf("got  %s\nwant %s", snap.Data, want)
+	}
+
+	if snap.Source != "pflag:app" {
+		t.Fatalf("got source %s", snap.Source)
+	}
+
+	c := New(WithSource(CliPFlagSet(fs, FlagOption{IncludeDefaults: true})))
+	if got := c.Get("unset").String(""); got != "default" {
+		t.Fatalf("got default %q", got)
+	}
+
+	if got := c.Get("timeout").Duration(0); got != time.Second*90 {
+		t.Fatalf("got timeout %v", got)
+	}
+}
+
+func TestCliPFlagSetNotParsed(t *testing.T) {
+	fs := pflag.NewFlagSet("app", pflag.ContinueOnError)
+
+	if _, err := CliPFlagSet(fs).Load(); err == nil {
+		t.Fatal("unparsed flag set loaded")
+	}
+}
@@ -0,0 +1,752 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// nodeKind is type of tree node
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	objectNode
+	arrayNode
+)
+
+// node is immutable config tree node, changes return new node
+// sharing unchanged children with previous version
+type node struct {
+	kind nodeKind
+
+	// object keys in source order
+	keys   []string
+	fields map[string]*node
+
+	// array elements
+	items []*node
+
+	// scalar value, either string, bool, json.Number or nil
+	val interface{}
+
+	// metadata of source providing the value
+	meta *Metadata
+}
+
+// treeReader read values into immutable tree
+type treeReader struct{}
+
+// TreeReader return reader of immutable ordered tree with precise numbers
+// and source metadata of every value, see Config.Origin
+func TreeReader() Reader {
+	return &treeReader{}
+}
+
+func (t *treeReader) Read(snap *Snapshot) (Values, error) {
+	if snap == nil {
+		return nil, errors.New("snapshot is nil")
+	}
+
+	root := snap.tree
+	if root == nil {
+		meta := snap.Metadata
+
+		var err error
+		if root, err = parseNode(snap.Data, &meta); err != nil {
+			// keep raw data as string like json reader
+			root = &node{val: string(snap.Data), meta: &meta}
+		}
+	}
+
+	return &treeValues{root: root}, nil
+}
+
+// treeMerger merge source trees without json round trip of unchanged subtrees
+type treeMerger struct{}
+
+// TreeMerger return merger producing snapshot with parsed tree, which is read
+// by TreeReader without parsing merged data again, later source override values
+// of earlier source including false, zero and null values
+func TreeMerger() Merger {
+	return &treeMerger{}
+}
+
+// parse return copy of snapshot along with its parsed tree
+func (t *treeMerger) parse(snap *Snapshot) (*Snapshot, error) {
+	if snap == nil || len(snap.Data) == 0 || snap.tree != nil {
+		return snap, nil
+	}
+
+	meta := snap.Metadata
+
+	n, err := parseNode(snap.Data, &meta)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", snap.Source, err)
+	}
+
+	return &Snapshot{
+		Data:       snap.Data,
+		Metadata:   snap.Metadata,
+		Components: snap.Components,
+		tree:       n,
+		checksum:   snap.checksum,
+	}, nil
+}
+
+func (t *treeMerger) Merge(snaps ...*Snapshot) (*Snapshot, error) {
+	root := &node{kind: objectNode, fields: map[string]*node{}}
+
+	components := make([]Metadata, 0, len(snaps))
+	for _, m := range snaps {
+		if m == nil {
+			continue
+		}
+
+		components = append(components, m.Metadata)
+
+		if len(m.Data) == 0 && m.tree == nil {
+			continue
+		}
+
+		n := m.tree
+		if n == nil {
+			meta := m.Metadata
+
+			var err error
+			if n, err = parseNode(m.Data, &meta); err != nil {
+				return nil, fmt.Errorf("%s: %v", m.Source, err)
+			}
+		}
+
+		if n.kind != objectNode {
+			return nil, fmt.Errorf("%s: config must be an object", m.Source)
+		}
+
+		root = mergeNode(root, n)
+	}
+
+	b, err := root.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{
+		Data: b,
+		Metadata: Metadata{
+			Source:    "merge",
+			Version:   checksum(b),
+			Format:    "json",
+			Timestamp: time.Now(),
+		},
+		Components: components,
+		tree:       root,
+	}
+
+	return snap, nil
+}
+
+// treeValues is values backed by immutable tree, modifying values
+// replace the root leaving previous tree untouched
+type treeValues struct {
+	root *node
+}
+
+func (t *treeValues) Get(path ...string) Value {
+	segs, err := resolvePath(path)
+	if err != nil {
+		return &jsonValue{Json: newJSON(nil), path: strings.Join(path, "."), err: err}
+	}
+
+	n, exists := t.root.get(segs)
+
+	return newNodeValue(n, formatPath(segs), exists)
+}
+
+func (t *treeValues) Set(val interface{}, path ...string) {
+	segs, err := resolvePath(path)
+	if err != nil {
+		return
+	}
+
+	n, err := nodeOf(val, &Metadata{Source: "runtime", Timestamp: time.Now()})
+	if err != nil {
+		return
+	}
+
+	t.root = t.root.set(segs, n)
+}
+
+func (t *treeValues) Del(path ...string) {
+	segs, err := resolvePath(path)
+	if err != nil {
+		return
+	}
+
+	// delete the tree?
+	if len(segs) == 0 {
+		t.root = &node{kind: objectNode, fields: map[string]*node{}}
+		return
+	}
+
+	t.root = t.root.del(segs)
+}
+
+func (t *treeValues) Bytes() []byte {
+	b, _ := t.root.MarshalJSON()
+	return b
+}
+
+// Map return copy of values, modifying it doesn't affect the values
+func (t *treeValues) Map() map[string]interface{} {
+	m, _ := t.root.value().(map[string]interface{})
+	return m
+}
+
+func (t *treeValues) Scan(v interface{}) error {
+	b, err := t.root.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// clone return values sharing the immutable tree
+func (t *treeValues) clone() Values {
+	return &treeValues{root: t.root}
+}
+
+// origin return metadata of source providing value of path
+func (t *treeValues) origin(segs []pathSegment) (Metadata, bool) {
+	n, ok := t.root.get(segs)
+	if !ok || n.meta == nil {
+		return Metadata{}, false
+	}
+
+	return *n.meta, true
+}
+
+// nodeValue is value of object or array node, getters of scalar types
+// fail on placeholder of node kind without converting the node, other
+// getters convert the node only when called
+type nodeValue struct {
+	*jsonValue
+
+	node *node
+}
+
+// newNodeValue return value of node, scalar node is read directly
+func newNodeValue(n *node, path string, exists bool) Value {
+	var v interface{}
+	switch {
+	case n == nil:
+	case n.kind == objectNode:
+		v = map[string]interface{}{}
+	case n.kind == arrayNode:
+		v = []interface{}{}
+	default:
+		v = n.val
+	}
+
+	j := &jsonValue{Json: newJSON(v), path: path, exists: exists}
+	if v == nil || n.kind == scalarNode {
+		return j
+	}
+
+	return &nodeValue{jsonValue: j, node: n}
+}
+
+// decoded return value of decoded json tree of node
+func (v *nodeValue) decoded() *jsonValue {
+	return &jsonValue{Json: newJSON(v.node.value()), path: v.path, exists: v.exists}
+}
+
+func (v *nodeValue) StringSlice(def []string) []string {
+	return v.decoded().StringSlice(def)
+}
+
+func (v *nodeValue) StringMap(def map[string]string) map[string]string {
+	return v.decoded().StringMap(def)
+}
+
+func (v *nodeValue) IntSlice(def []int) []int {
+	return v.decoded().IntSlice(def)
+}
+
+func (v *nodeValue) IntSliceE() ([]int, error) {
+	return v.decoded().IntSliceE()
+}
+
+func (v *nodeValue) Float64Slice(def []float64) []float64 {
+	return v.decoded().Float64Slice(def)
+}
+
+func (v *nodeValue) Float64SliceE() ([]float64, error) {
+	return v.decoded().Float64SliceE()
+}
+
+func (v *nodeValue) Map(def map[string]interface{}) map[string]interface{} {
+	m, err := v.MapE()
+	if err != nil {
+		return def
+	}
+
+	return m
+}
+
+// MapE return decoded object, which is a new copy already
+func (v *nodeValue) MapE() (map[string]interface{}, error) {
+	m, ok := v.node.value().(map[string]interface{})
+	if !ok {
+		return nil, v.typeError("map", nil)
+	}
+
+	return m, nil
+}
+
+func (v *nodeValue) Scan(val interface{}) error {
+	b, err := v.node.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, val)
+}
+
+// Bytes return json of node keeping object keys in source order
+func (v *nodeValue) Bytes() []byte {
+	b, err := v.node.MarshalJSON()
+	if err != nil {
+		return []byte{}
+	}
+
+	return b
+}
+
+// parseNode parse json data into tree, every node refer to given metadata
+func parseNode(data []byte, meta *Metadata) (*node, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return &node{kind: objectNode, fields: map[string]*node{}, meta: meta}, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	n, err := decodeNode(dec, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+
+	return n, nil
+}
+
+// decodeNode decode next json value from token stream
+func decodeNode(dec *json.Decoder, meta *Metadata) (*node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return &node{val: tok, meta: meta}, nil
+	}
+
+	switch delim {
+	case '{':
+		n := &node{kind: objectNode, fields: map[string]*node{}, meta: meta}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			key, _ := tok.(string)
+
+			child, err := decodeNode(dec, meta)
+			if err != nil {
+				return nil, err
+			}
+
+			// duplicated key override previous value in place
+			if _, ok := n.fields[key]; !ok {
+				n.keys = append(n.keys, key)
+			}
+
+			n.fields[key] = child
+		}
+
+		// closing brace
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		return n, nil
+	case '[':
+		n := &node{kind: arrayNode, items: []*node{}, meta: meta}
+		for dec.More() {
+			child, err := decodeNode(dec, meta)
+			if err != nil {
+				return nil, err
+			}
+
+			n.items = append(n.items, child)
+		}
+
+		// closing bracket
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", delim)
+	}
+}
+
+// nodeOf convert go value into tree, values other than decoded
+// json tree are converted through json encoding
+func nodeOf(v interface{}, meta *Metadata) (*node, error) {
+	switch val := v.(type) {
+	case nil, string, bool, json.Number:
+		return &node{val: val, meta: meta}, nil
+	case map[string]interface{}:
+		n := &node{kind: objectNode, keys: sortedKeys(val), fields: make(map[string]*node, len(val)), meta: meta}
+		for k, child := range val {
+			c, err := nodeOf(child, meta)
+			if err != nil {
+				return nil, err
+			}
+
+			n.fields[k] = c
+		}
+
+		return n, nil
+	case []interface{}:
+		n := &node{kind: arrayNode, items: make([]*node, len(val)), meta: meta}
+		for i, child := range val {
+			c, err := nodeOf(child, meta)
+			if err != nil {
+				return nil, err
+			}
+
+			n.items[i] = c
+		}
+
+		return n, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return parseNode(b, meta)
+	}
+}
+
+// mergeNode return dst overridden by src, objects are merged by key
+// while other values are replaced, unchanged nodes are shared
+func mergeNode(dst, src *node) *node {
+	if dst == nil {
+		return src
+	}
+
+	if src.kind != objectNode || dst.kind != objectNode {
+		return src
+	}
+
+	n := &node{
+		kind:   objectNode,
+		keys:   make([]string, len(dst.keys), len(dst.keys)+len(src.keys)),
+		fields: make(map[string]*node, len(dst.fields)+len(src.fields)),
+		meta:   src.meta,
+	}
+
+	copy(n.keys, dst.keys)
+	for k, child := range dst.fields {
+		n.fields[k] = child
+	}
+
+	for _, k := range src.keys {
+		prev, ok := n.fields[k]
+		if !ok {
+			n.keys = append(n.keys, k)
+		}
+
+		n.fields[k] = mergeNode(prev, src.fields[k])
+	}
+
+	return n
+}
+
+// get return node of path
+func (n *node) get(segs []pathSegment) (*node, bool) {
+	for _, seg := range segs {
+		if n == nil {
+			return nil, false
+		}
+
+		if seg.isIndex {
+			if n.kind != arrayNode {
+				return nil, false
+			}
+
+			i, ok := sliceIndex(len(n.items), seg.index)
+			if !ok {
+				return nil, false
+			}
+
+			n = n.items[i]
+			continue
+		}
+
+		if n.kind != objectNode {
+			return nil, false
+		}
+
+		child, ok := n.fields[seg.key]
+		if !ok {
+			return nil, false
+		}
+
+		n = child
+	}
+
+	return n, n != nil
+}
+
+// set return copy of node with value of path replaced, creating missing
+// objects and appending to arrays, only nodes along path are copied.
+// Node is returned unchanged when index of path is out of array bounds
+func (n *node) set(segs []pathSegment, val *node) *node {
+	res, ok := n.trySet(segs, val)
+	if !ok {
+		return n
+	}
+
+	return res
+}
+
+// trySet set value of path, reporting false for index out of bounds
+func (n *node) trySet(segs []pathSegment, val *node) (*node, bool) {
+	if len(segs) == 0 {
+		return val, true
+	}
+
+	seg := segs[0]
+	if seg.isIndex {
+		var items []*node
+		if n != nil && n.kind == arrayNode {
+			items = n.items
+		}
+
+		i, ok := sliceIndex(len(items), seg.index)
+		if !ok && i != len(items) {
+			// array is extended by appending only
+			return nil, false
+		}
+
+		var child *node
+		if i < len(items) {
+			child = items[i]
+		}
+
+		child, ok = child.trySet(segs[1:], val)
+		if !ok {
+			return nil, false
+		}
+
+		res := &node{kind: arrayNode, items: make([]*node, len(items), len(items)+1), meta: val.meta}
+		copy(res.items, items)
+
+		if i == len(items) {
+			res.items = append(res.items, child)
+		} else {
+			res.items[i] = child
+		}
+
+		return res, true
+	}
+
+	var child *node
+	if n != nil && n.kind == objectNode {
+		child = n.fields[seg.key]
+	}
+
+	child, ok := child.trySet(segs[1:], val)
+	if !ok {
+		return nil, false
+	}
+
+	res := &node{kind: objectNode, fields: map[string]*node{}, meta: val.meta}
+	if n != nil && n.kind == objectNode {
+		res.keys = n.keys
+		res.fields = make(map[string]*node, len(n.fields)+1)
+		for k, c := range n.fields {
+			res.fields[k] = c
+		}
+	}
+
+	if _, ok := res.fields[seg.key]; !ok {
+		res.keys = append(res.keys[:len(res.keys):len(res.keys)], seg.key)
+	}
+
+	res.fields[seg.key] = child
+	return res, true
+}
+
+// del return copy of node with path deleted, only nodes along path are copied
+func (n *node) del(segs []pathSegment) *node {
+	if n == nil || len(segs) == 0 {
+		return n
+	}
+
+	seg := segs[0]
+	last := len(segs) == 1
+
+	if seg.isIndex {
+		if n.kind != arrayNode {
+			return n
+		}
+
+		i, ok := sliceIndex(len(n.items), seg.index)
+		if !ok {
+			return n
+		}
+
+		res := &node{kind: arrayNode, meta: n.meta}
+		if last {
+			res.items = append(append(make([]*node, 0, len(n.items)-1), n.items[:i]...), n.items[i+1:]...)
+			return res
+		}
+
+		res.items = make([]*node, len(n.items))
+		copy(res.items, n.items)
+		res.items[i] = n.items[i].del(segs[1:])
+		return res
+	}
+
+	if n.kind != objectNode {
+		return n
+	}
+
+	child, ok := n.fields[seg.key]
+	if !ok {
+		return n
+	}
+
+	res := &node{kind: objectNode, keys: n.keys, fields: make(map[string]*node, len(n.fields)), meta: n.meta}
+	for k, c := range n.fields {
+		res.fields[k] = c
+	}
+
+	if !last {
+		res.fields[seg.key] = child.del(segs[1:])
+		return res
+	}
+
+	delete(res.fields, seg.key)
+
+	res.keys = make([]string, 0, len(n.keys)-1)
+	for _, k := range n.keys {
+		if k != seg.key {
+			res.keys = append(res.keys, k)
+		}
+	}
+
+	return res
+}
+
+// value return decoded json tree of node
+func (n *node) value() interface{} {
+	if n == nil {
+		return nil
+	}
+
+	switch n.kind {
+	case objectNode:
+		m := make(map[string]interface{}, len(n.fields))
+		for k, child := range n.fields {
+			m[k] = child.value()
+		}
+		return m
+	case arrayNode:
+		l := make([]interface{}, len(n.items))
+		for i, child := range n.items {
+			l[i] = child.value()
+		}
+		return l
+	default:
+		return n.val
+	}
+}
+
+// MarshalJSON encode node keeping object keys in source order
+func (n *node) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := n.encode(&buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (n *node) encode(buf *bytes.Buffer) error {
+	if n == nil {
+		buf.WriteString("null")
+		return nil
+	}
+
+	switch n.kind {
+	case objectNode:
+		buf.WriteByte('{')
+		for i, k := range n.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			b, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+
+			buf.Write(b)
+			buf.WriteByte(':')
+
+			if err := n.fields[k].encode(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case arrayNode:
+		buf.WriteByte('[')
+		for i, child := range n.items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := child.encode(buf); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		b, err := json.Marshal(n.val)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(b)
+	}
+
+	return nil
+}
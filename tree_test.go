@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// benchReaders compare default json reader and merger against tree
+var benchReaders = []struct {
+	name string
+	opts []Option
+}{
+	{name: "json"},
+	{name: "tree", opts: []Option{WithReader(TreeReader()), WithMerger(TreeMerger())}},
+}
+
+// benchConfig return routing config with n upstreams
+func benchConfig(b *testing.B, n int) []byte {
+	upstreams := make([]interface{}, n)
+	for i := range upstreams {
+		upstreams[i] = map[string]interface{}{
+			"host":    fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+			"port":    8000 + i%1000,
+			"weight":  i % 10,
+			"id":      int64(1)<<60 + int64(i),
+			"enabled": i%2 == 0,
+		}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"name":      "router",
+		"upstreams": upstreams,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return data
+}
+
+// benchOptions return options with new sources, so sources are parsed again
+func benchOptions(data []byte, opts []Option) []Option {
+	return append([]Option{
+		WithSource(Bytes(data, "json")),
+		WithSource(Memory(map[string]interface{}{"region": "eu"})),
+	}, opts...)
+}
+
+func BenchmarkLoad(b *testing.B) {
+	data := benchConfig(b, 1000)
+
+	for _, r := range benchReaders {
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				New(benchOptions(data, r.opts)...)
+			}
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	data := benchConfig(b, 1000)
+
+	for _, r := range benchReaders {
+		b.Run(r.name, func(b *testing.B) {
+			c := New(benchOptions(data, r.opts)...)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				c.Get("upstreams[999].host").String("")
+				c.Get("upstreams").Exists()
+			}
+		})
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	data := benchConfig(b, 1000)
+
+	for _, r := range benchReaders {
+		b.Run(r.name, func(b *testing.B) {
+			c := New(benchOptions(data, r.opts)...)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				c.Set(i, "counter")
+			}
+		})
+	}
+}
+
+func TestTreeAliasMigrateKeepOrigin(t *testing.T) {
+	c := New(
+		WithSource(Memory(map[string]interface{}{
+			"db": map[string]interface{}{"host": "a", "port": 1},
+		})),
+		WithSource(Bytes([]byte(`{"database":{"port":2}}`), "json")),
+		WithReader(TreeReader()),
+		WithMerger(TreeMerger()),
+		Alias("db", "database"),
+	)
+
+	if got := c.Get("database", "host").String(""); got != "a" {
+		t.Fatalf("got host %q", got)
+	}
+
+	if got := c.Get("database", "port").Int(0); got != 2 {
+		t.Fatalf("got port %d", got)
+	}
+
+	meta, ok := c.Origin("database.host")
+	if !ok || meta.Source != "memory" {
+		t.Fatalf("host origin %+v", meta)
+	}
+
+	if meta, _ = c.Origin("database.port"); meta.Source == "memory" || meta.Source == "merge" {
+		t.Fatalf("port origin %+v", meta)
+	}
+}
+
+func TestTreeNodeValue(t *testing.T) {
+	c := New(
+		WithSource(Bytes([]byte(`{"a":{"z":1,"y":[1,2]},"l":[1.5,2]}`), "json")),
+		WithReader(TreeReader()),
+		WithMerger(TreeMerger()),
+	)
+
+	a := c.Get("a")
+	if got := string(a.Bytes()); got != `{"z":1,"y":[1,2]}` {
+		t.Fatalf("bytes %s", got)
+	}
+
+	if _, err := a.IntE(); err == nil {
+		t.Fatal("object converted to int")
+	}
+
+	m := a.Map(nil)
+	m["z"] = 2
+	if got := c.Get("a", "z").Int(0); got != 1 {
+		t.Fatalf("map modified values, got %d", got)
+	}
+
+	if got := c.Get("a", "y").IntSlice(nil); len(got) != 2 || got[1] != 2 {
+		t.Fatalf("int slice %v", got)
+	}
+
+	if got := c.Get("l").Float64Slice(nil); len(got) != 2 || got[0] != 1.5 {
+		t.Fatalf("float slice %v", got)
+	}
+
+	var v struct{ Z int }
+	if err := a.Scan(&v); err != nil || v.Z != 1 {
+		t.Fatalf("scan %+v %v", v, err)
+	}
+}
+
+func TestTreeNormalizedKeyOrder(t *testing.T) {
+	c := New(
+		WithSource(Bytes([]byte(`{"Zeta":1,"Alpha":{"Y":1,"B":2},"Mid":[{"Q":1,"A":2}]}`), "json")),
+		WithKeyNormalizer(LowerCaseKeys),
+		WithReader(TreeReader()),
+		WithMerger(TreeMerger()),
+	)
+
+	want := `{"zeta":1,"alpha":{"y":1,"b":2},"mid":[{"q":1,"a":2}]}`
+	if got := string(c.Snapshot().Data); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if meta, ok := c.Origin("alpha.y"); !ok || meta.Source == "merge" {
+		t.Fatalf("origin %+v", meta)
+	}
+}
@@ -1,9 +1,17 @@
 package config
 
-import "time"
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+)
 
 // Value represent config field value of any type
+// Methods with E suffix return error naming the path and actual type
+// when the value is missing or cannot be converted, instead of default
 type Value interface {
+	Exists() bool
 	Bool(def bool) bool
 	Int(def int) int
 	String(def string) string
@@ -11,8 +19,38 @@ type Value interface {
 	Duration(def time.Duration) time.Duration
 	StringSlice(def []string) []string
 	StringMap(def map[string]string) map[string]string
+	Int64(def int64) int64
+	Uint(def uint) uint
+	Uint64(def uint64) uint64
+	Time(def time.Time, layouts ...string) time.Time
+	ByteSize(def uint64) uint64
+	URL(def *url.URL) *url.URL
+	IP(def net.IP) net.IP
+	CIDR(def *net.IPNet) *net.IPNet
+	Regexp(def *regexp.Regexp) *regexp.Regexp
+	IntSlice(def []int) []int
+	Float64Slice(def []float64) []float64
+	Map(def map[string]interface{}) map[string]interface{}
 	Scan(val interface{}) error
 	Bytes() []byte
+
+	BoolE() (bool, error)
+	IntE() (int, error)
+	StringE() (string, error)
+	Float64E() (float64, error)
+	DurationE() (time.Duration, error)
+	Int64E() (int64, error)
+	UintE() (uint, error)
+	Uint64E() (uint64, error)
+	TimeE(layouts ...string) (time.Time, error)
+	ByteSizeE() (uint64, error)
+	URLE() (*url.URL, error)
+	IPE() (net.IP, error)
+	CIDRE() (*net.IPNet, error)
+	RegexpE() (*regexp.Regexp, error)
+	IntSliceE() ([]int, error)
+	Float64SliceE() ([]float64, error)
+	MapE() (map[string]interface{}, error)
 }
 
 // Values contains collection of config value
@@ -34,3 +72,9 @@ type Reader interface {
 type Merger interface {
 	Merge(...*Snapshot) (*Snapshot, error)
 }
+
+// snapshotParser parse source snapshot into representation used by merger,
+// parsed snapshots are reused until source checksum changed
+type snapshotParser interface {
+	parse(*Snapshot) (*Snapshot, error)
+}
@@ -1,19 +1,32 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
 
 	simple "github.com/bitly/go-simplejson"
-	"github.com/imdario/mergo"
 )
 
+// ErrNotFound is returned by strict getters when config path doesn't exist
+var ErrNotFound = errors.New("path not found")
+
 type jsonValue struct {
 	*simple.Json
+
+	// resolved path of value
+	path string
+
+	// whether path exists
+	exists bool
+
+	// error of invalid path
+	err error
 }
 
 type jsonValues struct {
@@ -33,26 +46,49 @@ func (j *jsonReader) Read(snap *Snapshot) (Values, error) {
 
 type jsonMerger struct{}
 
+// parse return copy of snapshot along with its decoded data
+func (j *jsonMerger) parse(snap *Snapshot) (*Snapshot, error) {
+	if snap == nil || len(snap.Data) == 0 || snap.parsed != nil {
+		return snap, nil
+	}
+
+	var data map[string]interface{}
+	if err := decodeJSON(snap.Data, &data); err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Data:       snap.Data,
+		Metadata:   snap.Metadata,
+		Components: snap.Components,
+		parsed:     data,
+		checksum:   snap.checksum,
+	}, nil
+}
+
 func (j *jsonMerger) Merge(snaps ...*Snapshot) (*Snapshot, error) {
 	var merged map[string]interface{}
 
+	components := make([]Metadata, 0, len(snaps))
 	for _, m := range snaps {
 		if m == nil {
 			continue
 		}
 
+		components = append(components, m.Metadata)
+
 		if m.Data == nil || len(m.Data) == 0 {
 			continue
 		}
 
-		var data map[string]interface{}
-		if err := json.Unmarshal(m.Data, &data); err != nil {
-			return nil, err
+		data, ok := m.parsed.(map[string]interface{})
+		if !ok {
+			if err := decodeJSON(m.Data, &data); err != nil {
+				return nil, err
+			}
 		}
 
-		if err := mergo.Map(&merged, data, mergo.WithOverride); err != nil {
-			return nil, err
-		}
+		merged = mergeTree(merged, data)
 	}
 
 	b, err := json.Marshal(merged)
@@ -62,12 +98,56 @@ func (j *jsonMerger) Merge(snaps ...*Snapshot) (*Snapshot, error) {
 
 	snap := &Snapshot{
 		Data: b,
+		Metadata: Metadata{
+			Source:    "merge",
+			Version:   checksum(b),
+			Format:    "json",
+			Timestamp: time.Now(),
+		},
+		Components: components,
+		parsed:     merged,
 	}
 
 	return snap, nil
 }
 
+// mergeTree return dst with src merged over it, later values override earlier
+// except null values, which are skipped. Neither tree is modified, objects
+// along overridden values are copied while the rest is shared
+func mergeTree(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		return src
+	}
+
+	res := make(map[string]interface{}, len(dst)+len(src))
+	for k, v := range dst {
+		res[k] = v
+	}
+
+	for k, v := range src {
+		if v == nil {
+			continue
+		}
+
+		if sm, ok := v.(map[string]interface{}); ok {
+			if dm, ok := res[k].(map[string]interface{}); ok {
+				res[k] = mergeTree(dm, sm)
+				continue
+			}
+		}
+
+		res[k] = v
+	}
+
+	return res
+}
+
 func newJSONValues(snap *Snapshot) (Values, error) {
+	// parsed tree is shared, Set and Del copy modified path only
+	if snap.parsed != nil {
+		return &jsonValues{snap: snap, sj: newJSON(snap.parsed)}, nil
+	}
+
 	j := simple.New()
 	if err := j.UnmarshalJSON(snap.Data); err != nil {
 		j.SetPath(nil, string(snap.Data))
@@ -76,79 +156,186 @@ func newJSONValues(snap *Snapshot) (Values, error) {
 	return &jsonValues{snap: snap, sj: j}, nil
 }
 
+func (j *jsonValue) Exists() bool {
+	return j.exists
+}
+
 func (j *jsonValue) Bool(def bool) bool {
+	b, err := j.BoolE()
+	if err != nil {
+		return def
+	}
+
+	return b
+}
+
+func (j *jsonValue) BoolE() (bool, error) {
+	if !j.exists {
+		return false, j.notFound()
+	}
+
 	b, err := j.Json.Bool()
 	if err == nil {
-		return b
+		return b, nil
 	}
 
 	str, ok := j.Interface().(string)
 	if !ok {
-		return def
+		return false, j.typeError("bool", nil)
 	}
 
 	b, err = strconv.ParseBool(str)
 	if err != nil {
-		return def
+		return false, j.typeError("bool", err)
 	}
 
-	return b
+	return b, nil
 }
 
 func (j *jsonValue) Int(def int) int {
-	i, err := j.Json.Int()
+	i, err := j.IntE()
+	if err != nil {
+		return def
+	}
+
+	return i
+}
+
+func (j *jsonValue) IntE() (int, error) {
+	if !j.exists {
+		return 0, j.notFound()
+	}
+
+	i64, err := j.int64()
 	if err == nil {
-		return i
+		if int64(int(i64)) != i64 {
+			return 0, j.typeError("int", errors.New("value out of range"))
+		}
+
+		return int(i64), nil
 	}
 
 	str, ok := j.Interface().(string)
 	if !ok {
-		return def
+		return 0, j.numberError("int", err)
 	}
 
-	i, err = strconv.Atoi(str)
+	i, err := strconv.Atoi(str)
 	if err != nil {
-		return def
+		return 0, j.typeError("int", err)
 	}
 
-	return i
+	return i, nil
 }
 
 func (j *jsonValue) String(def string) string {
-	return j.Json.MustString(def)
+	str, err := j.StringE()
+	if err != nil {
+		return def
+	}
+
+	return str
+}
+
+func (j *jsonValue) StringE() (string, error) {
+	if !j.exists {
+		return "", j.notFound()
+	}
+
+	str, err := j.Json.String()
+	if err != nil {
+		return "", j.typeError("string", nil)
+	}
+
+	return str, nil
 }
 
 func (j *jsonValue) Float64(def float64) float64 {
+	f, err := j.Float64E()
+	if err != nil {
+		return def
+	}
+
+	return f
+}
+
+func (j *jsonValue) Float64E() (float64, error) {
+	if !j.exists {
+		return 0, j.notFound()
+	}
+
 	f, err := j.Json.Float64()
 	if err == nil {
-		return f
+		return f, nil
 	}
 
 	str, ok := j.Interface().(string)
 	if !ok {
-		return def
+		return 0, j.typeError("float64", nil)
 	}
 
 	f, err = strconv.ParseFloat(str, 64)
 	if err != nil {
-		return def
+		return 0, j.typeError("float64", err)
 	}
 
-	return f
+	return f, nil
 }
 
 func (j *jsonValue) Duration(def time.Duration) time.Duration {
-	v, err := j.Json.String()
+	d, err := j.DurationE()
 	if err != nil {
 		return def
 	}
 
+	return d
+}
+
+func (j *jsonValue) DurationE() (time.Duration, error) {
+	if !j.exists {
+		return 0, j.notFound()
+	}
+
+	v, err := j.Json.String()
+	if err != nil {
+		return 0, j.typeError("duration", nil)
+	}
+
 	value, err := time.ParseDuration(v)
 	if err != nil {
-		return def
+		return 0, j.typeError("duration", err)
+	}
+
+	return value, nil
+}
+
+// notFound return error of missing or invalid path
+func (j *jsonValue) notFound() error {
+	if j.err != nil {
+		return fmt.Errorf("config: %w", j.err)
+	}
+
+	return fmt.Errorf("config: %s: %w", j.path, ErrNotFound)
+}
+
+// numberError return error of value which cannot be converted to integer
+// type typ, err is reported only for numbers out of range of typ
+func (j *jsonValue) numberError(typ string, err error) error {
+	if _, ok := j.Interface().(json.Number); ok {
+		return j.typeError(typ, err)
+	}
+
+	return j.typeError(typ, nil)
+}
+
+// typeError return error of value which cannot be converted to typ
+func (j *jsonValue) typeError(typ string, err error) error {
+	v := j.Interface()
+	if err != nil {
+		return fmt.Errorf("config: %s: cannot parse %T %q as %s: %v", j.path, v, fmt.Sprint(v), typ, err)
 	}
 
-	return value
+	return fmt.Errorf("config: %s: cannot use %T as %s", j.path, v, typ)
 }
 
 func (j *jsonValue) StringSlice(def []string) []string {
@@ -199,31 +386,43 @@ func (j *jsonValue) Bytes() []byte {
 }
 
 func (j *jsonValues) Get(path ...string) Value {
-	return &jsonValue{j.sj.GetPath(resolvePath(path)...)}
+	segs, err := resolvePath(path)
+	if err != nil {
+		return &jsonValue{Json: &simple.Json{}, path: strings.Join(path, "."), err: err}
+	}
+
+	v, exists := getSegments(j.sj.Interface(), segs)
+
+	return &jsonValue{Json: newJSON(v), path: formatPath(segs), exists: exists}
 }
 
 func (j *jsonValues) Del(path ...string) {
-	path = resolvePath(path)
+	segs, err := resolvePath(path)
+	if err != nil {
+		return
+	}
 
 	// delete the tree?
-	if len(path) == 0 {
+	if len(segs) == 0 {
 		j.sj = simple.New()
 		return
 	}
 
-	if len(path) == 1 {
-		j.sj.Del(path[0])
+	j.sj = newJSON(delSegments(j.sj.Interface(), segs))
+}
+
+func (j *jsonValues) Set(val interface{}, path ...string) {
+	segs, err := resolvePath(path)
+	if err != nil {
 		return
 	}
 
-	vals := j.sj.GetPath(path[:len(path)-1]...)
-	vals.Del(path[len(path)-1])
-	j.sj.SetPath(path[:len(path)-1], vals.Interface())
-	return
+	j.sj = newJSON(setSegments(j.sj.Interface(), segs, val))
 }
 
-func (j *jsonValues) Set(val interface{}, path ...string) {
-	j.sj.SetPath(resolvePath(path), val)
+// clone return values sharing the tree, Set and Del copy modified path only
+func (j *jsonValues) clone() Values {
+	return &jsonValues{snap: j.snap, sj: newJSON(j.sj.Interface())}
 }
 
 func (j *jsonValues) Bytes() []byte {
@@ -231,8 +430,9 @@ func (j *jsonValues) Bytes() []byte {
 	return b
 }
 
+// Map return copy of values, modifying it doesn't affect the values
 func (j *jsonValues) Map() map[string]interface{} {
-	m, _ := j.sj.Map()
+	m, _ := copyTree(j.sj.Interface()).(map[string]interface{})
 	return m
 }
 
@@ -244,15 +444,47 @@ func (j *jsonValues) Scan(v interface{}) error {
 	return json.Unmarshal(b, v)
 }
 
-func resolvePath(path []string) []string {
-	if path == nil || len(path) == 0 {
+// copyTree deep copy maps and slices of decoded json tree
+func copyTree(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			m[k] = copyTree(child)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(val))
+		for i, child := range val {
+			l[i] = copyTree(child)
+		}
+		return l
+	default:
+		return v
+	}
+}
 
-		return []string{}
+// decodeJSON decode single json value keeping numbers as json.Number,
+// so integers are not rounded through float64
+func decodeJSON(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err := dec.Decode(v); err != nil {
+		return err
 	}
 
-	if len(path) == 1 {
-		return strings.Split(path[0], ".")
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
 	}
 
-	return path
+	return nil
+}
+
+// newJSON wrap decoded json tree
+func newJSON(v interface{}) *simple.Json {
+	sj := simple.New()
+	sj.SetPath(nil, v)
+
+	return sj
 }
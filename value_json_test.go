@@ -0,0 +1,224 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestUnsignedRejectNegative(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{"size": "1KB"})))
+
+	for _, v := range []interface{}{-1, int8(-1), int64(-1), -1.5, float32(-2)} {
+		c.Set(v, "n")
+
+		if u, err := c.Get("n").UintE(); err == nil {
+			t.Errorf("UintE of %v(%T): got %d, want error", v, v, u)
+		}
+
+		if u, err := c.Get("n").Uint64E(); err == nil {
+			t.Errorf("Uint64E of %v(%T): got %d, want error", v, v, u)
+		}
+
+		if u, err := c.Get("n").ByteSizeE(); err == nil {
+			t.Errorf("ByteSizeE of %v(%T): got %d, want error", v, v, u)
+		}
+	}
+
+	for _, v := range []interface{}{7, uint8(7), int64(7), 7.0} {
+		c.Set(v, "n")
+
+		if u, err := c.Get("n").Uint64E(); err != nil || u != 7 {
+			t.Errorf("Uint64E of %v(%T): got %d, %v", v, v, u, err)
+		}
+	}
+}
+
+func TestStrictGetters(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"timeout": "30 s",
+		"ttl":     "1m30s",
+		"debug":   "yes",
+		"enabled": "true",
+		"port":    "80a",
+		"workers": 4.5,
+		"db":      map[string]interface{}{"host": "a"},
+		"tags":    []interface{}{"a"},
+	})))
+
+	tests := []struct {
+		name string
+		get  func() (interface{}, error)
+		want interface{}
+		err  string
+	}{
+		{"duration", func() (interface{}, error) { return c.Get("ttl").DurationE() }, time.Second * 90, ""},
+		{"duration typo", func() (interface{}, error) { return c.Get("timeout").DurationE() },
+			time.Duration(0), `config: timeout: cannot parse string "30 s" as duration: time: unknown unit " s" in duration "30 s"`},
+		{"duration of object", func() (interface{}, error) { return c.Get("db").DurationE() },
+			time.Duration(0), `config: db: cannot use map[string]interface {} as duration`},
+		{"bool string", func() (interface{}, error) { return c.Get("enabled").BoolE() }, true, ""},
+		{"bool invalid", func() (interface{}, error) { return c.Get("debug").BoolE() },
+			false, `config: debug: cannot parse string "yes" as bool: strconv.ParseBool: parsing "yes": invalid syntax`},
+		{"bool of array", func() (interface{}, error) { return c.Get("tags").BoolE() },
+			false, `config: tags: cannot use []interface {} as bool`},
+		{"int invalid", func() (interface{}, error) { return c.Get("port").IntE() },
+			0, `config: port: cannot parse string "80a" as int: strconv.Atoi: parsing "80a": invalid syntax`},
+		{"int of float", func() (interface{}, error) { return c.Get("workers").IntE() }, 4, ""},
+		{"int of array", func() (interface{}, error) { return c.Get("tags").IntE() },
+			0, `config: tags: cannot use []interface {} as int`},
+		{"nested", func() (interface{}, error) { return c.Get("db", "host").IntE() },
+			0, `config: db.host: cannot parse string "a" as int: strconv.Atoi: parsing "a": invalid syntax`},
+		{"missing", func() (interface{}, error) { return c.Get("db", "port").IntE() },
+			0, `config: db.port: path not found`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || err.Error() != tt.err {
+				t.Fatalf("got error %v, want %s", err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// lenient getters fall back to default
+	if got := c.Get("timeout").Duration(time.Second); got != time.Second {
+		t.Fatalf("got duration %v", got)
+	}
+
+	if got := c.Get("port").Int(8080); got != 8080 {
+		t.Fatalf("got port %d", got)
+	}
+}
+
+func TestStrictGettersNotFound(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{"a": 1})))
+
+	for _, path := range []string{"missing", "a.b"} {
+		v := c.Get(path)
+		if v.Exists() {
+			t.Fatalf("%s exists", path)
+		}
+
+		if _, err := v.BoolE(); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("BoolE of %s: got %v", path, err)
+		}
+
+		if _, err := v.IntE(); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("IntE of %s: got %v", path, err)
+		}
+
+		if _, err := v.DurationE(); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("DurationE of %s: got %v", path, err)
+		}
+	}
+
+	// invalid path isn't reported as missing value
+	if _, err := c.Get("a[").IntE(); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("IntE of invalid path: got %v", err)
+	}
+}
+
+func TestJSONMergerMerge(t *testing.T) {
+	tests := []struct {
+		dst, src string
+		want     string
+	}{
+		{`{"a":{"b":{"c":1}}}`, `{"a":{"b":{"d":2}}}`, `{"a":{"b":{"c":1,"d":2}}}`},
+		{`{"a":1}`, `{"a":false}`, `{"a":false}`},
+		{`{"a":1}`, `{"a":0}`, `{"a":0}`},
+		{`{"a":1}`, `{"a":null}`, `{"a":1}`},
+		{`{"a":{"b":1}}`, `{"a":null}`, `{"a":{"b":1}}`},
+		{`{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{`{"a":{"b":1}}`, `{"a":2}`, `{"a":2}`},
+		{`{"a":1}`, `{"a":{"b":1}}`, `{"a":{"b":1}}`},
+		{`{"a":{"b":1}}`, `{"a":{}}`, `{"a":{"b":1}}`},
+		{`{}`, `{"a":1}`, `{"a":1}`},
+	}
+
+	m := &jsonMerger{}
+
+	for _, tt := range tests {
+		dst, err := m.parse(&Snapshot{Data: []byte(tt.dst)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		src, err := m.parse(&Snapshot{Data: []byte(tt.src)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		snap, err := m.Merge(dst, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(snap.Data) != tt.want {
+			t.Errorf("%s + %s: got %s, want %s", tt.dst, tt.src, snap.Data, tt.want)
+		}
+	}
+}
+
+func TestJSONMergerKeepSources(t *testing.T) {
+	m := &jsonMerger{}
+
+	dst, err := m.parse(&Snapshot{Data: []byte(`{"a":{"b":1},"c":{"d":1}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := m.parse(&Snapshot{Data: []byte(`{"a":{"e":2}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := m.Merge(dst, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []*Snapshot{dst, src} {
+		b, err := json.Marshal(s.parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != string(s.Data) {
+			t.Fatalf("parsed source modified: got %s, want %s", b, s.Data)
+		}
+	}
+
+	// objects which aren't overridden are shared
+	merged := snap.parsed.(map[string]interface{})
+	if fmt.Sprintf("%p", merged["c"]) != fmt.Sprintf("%p", dst.parsed.(map[string]interface{})["c"]) {
+		t.Fatal("unmodified object is copied")
+	}
+
+	// values share parsed tree and copy modified path only
+	values, err := (&jsonReader{}).Read(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values.Set(3, "c", "d")
+
+	if got := string(values.Bytes()); got != `{"a":{"b":1,"e":2},"c":{"d":3}}` {
+		t.Fatalf("got %s", got)
+	}
+
+	if b, _ := json.Marshal(snap.parsed); string(b) != `{"a":{"b":1,"e":2},"c":{"d":1}}` {
+		t.Fatalf("parsed snapshot modified by set: %s", b)
+	}
+}
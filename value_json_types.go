@@ -0,0 +1,517 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// byteSizeUnits multiplier of byte size units, SI units (KB) are power of 1000,
+// IEC (KiB) and single letter (K) units are power of 1024
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1e12,
+	"tib": 1 << 40,
+	"p":   1 << 50,
+	"pb":  1e15,
+	"pib": 1 << 50,
+}
+
+func (j *jsonValue) Int64(def int64) int64 {
+	i, err := j.Int64E()
+	if err != nil {
+		return def
+	}
+
+	return i
+}
+
+func (j *jsonValue) Int64E() (int64, error) {
+	if !j.exists {
+		return 0, j.notFound()
+	}
+
+	i, err := j.int64()
+	if err == nil {
+		return i, nil
+	}
+
+	str, ok := j.Interface().(string)
+	if !ok {
+		return 0, j.numberError("int64", err)
+	}
+
+	i, err = strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, j.typeError("int64", err)
+	}
+
+	return i, nil
+}
+
+func (j *jsonValue) Uint(def uint) uint {
+	u, err := j.UintE()
+	if err != nil {
+		return def
+	}
+
+	return u
+}
+
+func (j *jsonValue) UintE() (uint, error) {
+	if !j.exists {
+		return 0, j.notFound()
+	}
+
+	u, err := j.uint64()
+	if err == nil {
+		if uint64(uint(u)) != u {
+			return 0, j.typeError("uint", errors.New("value out of range"))
+		}
+
+		return uint(u), nil
+	}
+
+	str, ok := j.Interface().(string)
+	if !ok {
+		return 0, j.numberError("uint", err)
+	}
+
+	u, err = strconv.ParseUint(str, 10, 0)
+	if err != nil {
+		return 0, j.typeError("uint", err)
+	}
+
+	return uint(u), nil
+}
+
+func (j *jsonValue) Uint64(def uint64) uint64 {
+	u, err := j.Uint64E()
+	if err != nil {
+		return def
+	}
+
+	return u
+}
+
+func (j *jsonValue) Uint64E() (uint64, error) {
+	if !j.exists {
+		return 0, j.notFound()
+	}
+
+	u, err := j.uint64()
+	if err == nil {
+		return u, nil
+	}
+
+	str, ok := j.Interface().(string)
+	if !ok {
+		return 0, j.numberError("uint64", err)
+	}
+
+	u, err = strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, j.typeError("uint64", err)
+	}
+
+	return u, nil
+}
+
+// int64 convert number exactly, json.Number which is not an integer
+// is truncated through float64 as other float values
+func (j *jsonValue) int64() (int64, error) {
+	n, ok := j.Interface().(json.Number)
+	if !ok {
+		return j.Json.Int64()
+	}
+
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return 0, err
+	}
+
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, errors.New("value out of range")
+	}
+
+	return int64(f), nil
+}
+
+// uint64 convert number exactly, json.Number which is not an integer
+// is truncated through float64 as other float values
+func (j *jsonValue) uint64() (uint64, error) {
+	var f float64
+
+	switch n := j.Interface().(type) {
+	case json.Number:
+		if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return u, nil
+		}
+
+		v, err := n.Float64()
+		if err != nil {
+			return 0, err
+		}
+
+		f = v
+	case float32:
+		f = float64(n)
+	case float64:
+		f = n
+	default:
+		// values set at runtime keep their go type, negative
+		// signed ints would wrap around in conversion
+		v := reflect.ValueOf(n)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Int() < 0 {
+				return 0, errors.New("value out of range")
+			}
+
+			return uint64(v.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return v.Uint(), nil
+		}
+
+		return j.Json.Uint64()
+	}
+
+	if f < 0 || f >= math.MaxUint64 {
+		return 0, errors.New("value out of range")
+	}
+
+	return uint64(f), nil
+}
+
+func (j *jsonValue) Time(def time.Time, layouts ...string) time.Time {
+	t, err := j.TimeE(layouts...)
+	if err != nil {
+		return def
+	}
+
+	return t
+}
+
+// TimeE parse string value using given layouts in order, default to RFC3339
+func (j *jsonValue) TimeE(layouts ...string) (time.Time, error) {
+	if !j.exists {
+		return time.Time{}, j.notFound()
+	}
+
+	str, err := j.Json.String()
+	if err != nil {
+		return time.Time{}, j.typeError("time", nil)
+	}
+
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339Nano}
+	}
+
+	for _, layout := range layouts {
+		t, perr := time.Parse(layout, str)
+		if perr == nil {
+			return t, nil
+		}
+
+		err = perr
+	}
+
+	return time.Time{}, j.typeError("time", err)
+}
+
+func (j *jsonValue) ByteSize(def uint64) uint64 {
+	b, err := j.ByteSizeE()
+	if err != nil {
+		return def
+	}
+
+	return b
+}
+
+// ByteSizeE parse number of bytes or size string with unit, e.g. 512MB or 1.5GiB
+func (j *jsonValue) ByteSizeE() (uint64, error) {
+	if !j.exists {
+		return 0, j.notFound()
+	}
+
+	u, err := j.uint64()
+	if err == nil {
+		return u, nil
+	}
+
+	str, ok := j.Interface().(string)
+	if !ok {
+		return 0, j.numberError("byte size", err)
+	}
+
+	b, err := parseByteSize(str)
+	if err != nil {
+		return 0, j.typeError("byte size", err)
+	}
+
+	return b, nil
+}
+
+func (j *jsonValue) URL(def *url.URL) *url.URL {
+	u, err := j.URLE()
+	if err != nil {
+		return def
+	}
+
+	return u
+}
+
+func (j *jsonValue) URLE() (*url.URL, error) {
+	if !j.exists {
+		return nil, j.notFound()
+	}
+
+	str, err := j.Json.String()
+	if err != nil {
+		return nil, j.typeError("url", nil)
+	}
+
+	u, err := url.Parse(str)
+	if err != nil {
+		return nil, j.typeError("url", err)
+	}
+
+	return u, nil
+}
+
+func (j *jsonValue) IP(def net.IP) net.IP {
+	ip, err := j.IPE()
+	if err != nil {
+		return def
+	}
+
+	return ip
+}
+
+func (j *jsonValue) IPE() (net.IP, error) {
+	if !j.exists {
+		return nil, j.notFound()
+	}
+
+	str, err := j.Json.String()
+	if err != nil {
+		return nil, j.typeError("ip", nil)
+	}
+
+	ip := net.ParseIP(str)
+	if ip == nil {
+		return nil, j.typeError("ip", fmt.Errorf("invalid ip address"))
+	}
+
+	return ip, nil
+}
+
+func (j *jsonValue) CIDR(def *net.IPNet) *net.IPNet {
+	n, err := j.CIDRE()
+	if err != nil {
+		return def
+	}
+
+	return n
+}
+
+func (j *jsonValue) CIDRE() (*net.IPNet, error) {
+	if !j.exists {
+		return nil, j.notFound()
+	}
+
+	str, err := j.Json.String()
+	if err != nil {
+		return nil, j.typeError("cidr", nil)
+	}
+
+	_, n, err := net.ParseCIDR(str)
+	if err != nil {
+		return nil, j.typeError("cidr", err)
+	}
+
+	return n, nil
+}
+
+func (j *jsonValue) Regexp(def *regexp.Regexp) *regexp.Regexp {
+	re, err := j.RegexpE()
+	if err != nil {
+		return def
+	}
+
+	return re
+}
+
+func (j *jsonValue) RegexpE() (*regexp.Regexp, error) {
+	if !j.exists {
+		return nil, j.notFound()
+	}
+
+	str, err := j.Json.String()
+	if err != nil {
+		return nil, j.typeError("regexp", nil)
+	}
+
+	re, err := regexp.Compile(str)
+	if err != nil {
+		return nil, j.typeError("regexp", err)
+	}
+
+	return re, nil
+}
+
+func (j *jsonValue) IntSlice(def []int) []int {
+	l, err := j.IntSliceE()
+	if err != nil {
+		return def
+	}
+
+	return l
+}
+
+// IntSliceE convert array or comma separated string elements to int
+func (j *jsonValue) IntSliceE() ([]int, error) {
+	items, err := j.elements("[]int")
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]int, len(items))
+	for i, item := range items {
+		if res[i], err = item.IntE(); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (j *jsonValue) Float64Slice(def []float64) []float64 {
+	l, err := j.Float64SliceE()
+	if err != nil {
+		return def
+	}
+
+	return l
+}
+
+// Float64SliceE convert array or comma separated string elements to float64
+func (j *jsonValue) Float64SliceE() ([]float64, error) {
+	items, err := j.elements("[]float64")
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]float64, len(items))
+	for i, item := range items {
+		if res[i], err = item.Float64E(); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func (j *jsonValue) Map(def map[string]interface{}) map[string]interface{} {
+	m, err := j.MapE()
+	if err != nil {
+		return def
+	}
+
+	return m
+}
+
+// MapE return copy of object value
+func (j *jsonValue) MapE() (map[string]interface{}, error) {
+	if !j.exists {
+		return nil, j.notFound()
+	}
+
+	m, err := j.Json.Map()
+	if err != nil {
+		return nil, j.typeError("map", nil)
+	}
+
+	return copyTree(m).(map[string]interface{}), nil
+}
+
+// elements return array items, or comma separated items of string value
+func (j *jsonValue) elements(typ string) ([]*jsonValue, error) {
+	if !j.exists {
+		return nil, j.notFound()
+	}
+
+	var items []interface{}
+	switch v := j.Interface().(type) {
+	case []interface{}:
+		items = v
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			items = append(items, strings.TrimSpace(s))
+		}
+	default:
+		return nil, j.typeError(typ, nil)
+	}
+
+	res := make([]*jsonValue, len(items))
+	for i, item := range items {
+		res[i] = &jsonValue{Json: newJSON(item), path: fmt.Sprintf("%s[%d]", j.path, i), exists: true}
+	}
+
+	return res, nil
+}
+
+// parseByteSize parse size string such as 512MB, 1.5GiB or 1024
+func parseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	mul, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+
+	size := f * mul
+	if size > math.MaxUint64 {
+		return 0, fmt.Errorf("size overflow")
+	}
+
+	return uint64(size), nil
+}
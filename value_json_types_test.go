@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTypedGetters(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"big":      int64(9007199254740993),
+		"neg":      "-42",
+		"float":    1.9,
+		"huge":     1e20,
+		"date":     "2020-03-09",
+		"stamp":    "2020-03-09T07:10:43.123Z",
+		"si":       "1.5KB",
+		"iec":      "1.5KiB",
+		"short":    "2M",
+		"bytes":    512,
+		"badsize":  "1XB",
+		"url":      "https://user@example.com:8443/path?q=1",
+		"badurl":   "http://[::1",
+		"ip4":      "10.0.0.1",
+		"ip6":      "::1",
+		"badip":    "10.0.0",
+		"cidr":     "10.1.2.3/16",
+		"badcidr":  "10.0.0.1",
+		"re":       "^a+b$",
+		"badre":    "a(",
+		"ints":     "1, 2,3",
+		"intarr":   []interface{}{4, "5"},
+		"badints":  "1,x",
+		"floats":   "0.5,2",
+		"floatarr": []interface{}{1.5, 3},
+		"obj":      map[string]interface{}{"a": 1},
+	})))
+
+	get := func(path string, fn func(Value) (interface{}, error)) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			return fn(c.Get(path))
+		}
+	}
+
+	int64E := func(v Value) (interface{}, error) { return v.Int64E() }
+	timeE := func(layouts ...string) func(Value) (interface{}, error) {
+		return func(v Value) (interface{}, error) {
+			t, err := v.TimeE(layouts...)
+			return t.UTC().Format(time.RFC3339Nano), err
+		}
+	}
+	byteSizeE := func(v Value) (interface{}, error) { return v.ByteSizeE() }
+	urlE := func(v Value) (interface{}, error) { return v.URLE() }
+	ipE := func(v Value) (interface{}, error) { return v.IPE() }
+	cidrE := func(v Value) (interface{}, error) { return v.CIDRE() }
+	regexpE := func(v Value) (interface{}, error) { return v.RegexpE() }
+	intSliceE := func(v Value) (interface{}, error) { return v.IntSliceE() }
+	float64SliceE := func(v Value) (interface{}, error) { return v.Float64SliceE() }
+
+	tests := []struct {
+		name string
+		get  func() (interface{}, error)
+		want string
+		err  bool
+	}{
+		{"int64 exact", get("big", int64E), "9007199254740993", false},
+		{"int64 string", get("neg", int64E), "-42", false},
+		{"int64 truncated float", get("float", int64E), "1", false},
+		{"int64 out of range", get("huge", int64E), "0", true},
+		{"int64 of object", get("obj", int64E), "0", true},
+
+		{"time default layout", get("stamp", timeE()), "2020-03-09T07:10:43.123Z", false},
+		{"time layout", get("date", timeE("2006-01-02")), "2020-03-09T00:00:00Z", false},
+		{"time second layout", get("date", timeE(time.RFC3339, "2006-01-02")), "2020-03-09T00:00:00Z", false},
+		{"time no layout match", get("date", timeE(time.RFC3339)), "0001-01-01T00:00:00Z", true},
+
+		{"byte size SI", get("si", byteSizeE), "1500", false},
+		{"byte size IEC", get("iec", byteSizeE), "1536", false},
+		{"byte size short", get("short", byteSizeE), "2097152", false},
+		{"byte size number", get("bytes", byteSizeE), "512", false},
+		{"byte size unknown unit", get("badsize", byteSizeE), "0", true},
+
+		{"url", get("url", urlE), "https://user@example.com:8443/path?q=1", false},
+		{"url invalid", get("badurl", urlE), "<nil>", true},
+		{"ip4", get("ip4", ipE), "10.0.0.1", false},
+		{"ip6", get("ip6", ipE), "::1", false},
+		{"ip invalid", get("badip", ipE), "<nil>", true},
+		{"cidr network", get("cidr", cidrE), "10.1.0.0/16", false},
+		{"cidr without mask", get("badcidr", cidrE), "<nil>", true},
+		{"regexp", get("re", regexpE), "^a+b$", false},
+		{"regexp invalid", get("badre", regexpE), "<nil>", true},
+
+		{"int slice comma string", get("ints", intSliceE), "[1 2 3]", false},
+		{"int slice array", get("intarr", intSliceE), "[4 5]", false},
+		{"int slice invalid element", get("badints", intSliceE), "[]", true},
+		{"int slice of object", get("obj", intSliceE), "[]", true},
+		{"float64 slice comma string", get("floats", float64SliceE), "[0.5 2]", false},
+		{"float64 slice array", get("floatarr", float64SliceE), "[1.5 3]", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v", err)
+			}
+
+			if s := fmt.Sprint(got); s != tt.want {
+				t.Fatalf("got %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapGetterCopy(t *testing.T) {
+	c := New(WithSource(Memory(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":  "a",
+			"hosts": []interface{}{"a"},
+			"pool":  map[string]interface{}{"size": 1},
+		},
+	})))
+
+	m, err := c.Get("db").MapE()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m["host"] = "b"
+	m["hosts"].([]interface{})[0] = "b"
+	m["pool"].(map[string]interface{})["size"] = 2
+
+	if got := string(c.Get("db").Bytes()); got != `{"host":"a","hosts":["a"],"pool":{"size":1}}` {
+		t.Fatalf("returned map shares config values: %s", got)
+	}
+
+	def := map[string]interface{}{"d": 1}
+	if got := c.Get("db", "host").Map(def); fmt.Sprint(got) != fmt.Sprint(def) {
+		t.Fatalf("got %v for non-object value", got)
+	}
+}
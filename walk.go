@@ -0,0 +1,54 @@
+package config
+
+import (
+	"sort"
+	"strconv"
+)
+
+// walkTree call fn for every leaf of decoded json tree in key order,
+// empty objects and arrays are leaves as well
+func walkTree(node interface{}, path []string, segs []pathSegment, fn func(path []string, v Value) error) error {
+	switch val := node.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			break
+		}
+
+		for _, k := range sortedKeys(val) {
+			err := walkTree(val[k], append(path[:len(path):len(path)], k), appendSegment(segs, pathSegment{key: k}), fn)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case []interface{}:
+		if len(val) == 0 {
+			break
+		}
+
+		for i, item := range val {
+			elem := "[" + strconv.Itoa(i) + "]"
+
+			err := walkTree(item, append(path[:len(path):len(path)], elem), appendSegment(segs, pathSegment{index: i, isIndex: true}), fn)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return fn(path, &jsonValue{Json: newJSON(node), path: formatPath(segs), exists: true})
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
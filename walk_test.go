@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func newWalkConfig() Config {
+	return New(WithSource(Memory(map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a"},
+			"b",
+		},
+		"empty":       map[string]interface{}{},
+		"none":        []interface{}{},
+		"example.com": map[string]interface{}{"port": 80},
+		"q\"uote":     1,
+		"name":        "x",
+	})))
+}
+
+func TestKeys(t *testing.T) {
+	c := newWalkConfig()
+
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{nil, `empty,example.com,name,none,q"uote,servers`},
+		{[]string{"servers[0]"}, "host"},
+		{[]string{`"example.com"`}, "port"},
+		{[]string{"example.com", "port"}, ""},
+		{[]string{"empty"}, ""},
+		{[]string{"servers"}, ""},
+		{[]string{"missing"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(c.Keys(tt.path...), ","); got != tt.want {
+			t.Errorf("keys of %v: got %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	c := newWalkConfig()
+
+	var got []string
+	err := c.Walk(func(path []string, v Value) error {
+		got = append(got, strings.Join(path, "/")+"="+string(v.Bytes()))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`empty={}`,
+		`example.com/port=80`,
+		`name=x`,
+		`none=[]`,
+		`q"uote=1`,
+		`servers/[0]/host=a`,
+		`servers/[1]=b`,
+	}
+
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestWalkStopAtError(t *testing.T) {
+	c := newWalkConfig()
+	stop := errors.New("stop")
+
+	var visited []string
+	err := c.Walk(func(path []string, v Value) error {
+		visited = append(visited, strings.Join(path, "/"))
+		if path[0] == "name" {
+			return stop
+		}
+
+		return nil
+	})
+
+	if err != stop {
+		t.Fatalf("got error %v", err)
+	}
+
+	if got := strings.Join(visited, " "); got != "empty example.com/port name" {
+		t.Fatalf("walk continued after error, visited %s", got)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	c := newWalkConfig()
+
+	flat := c.Flatten()
+
+	keys := make([]string, 0, len(flat))
+	for k, v := range flat {
+		keys = append(keys, k+"="+string(v.Bytes()))
+	}
+
+	sort.Strings(keys)
+
+	want := []string{
+		`"example.com".port=80`,
+		`"q\"uote"=1`,
+		`empty={}`,
+		`name=x`,
+		`none=[]`,
+		`servers[0].host=a`,
+		`servers[1]=b`,
+	}
+
+	if strings.Join(keys, " ") != strings.Join(want, " ") {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+
+	// flatten keys are path expressions of Get
+	for k, v := range flat {
+		if got := c.Get(k); !got.Exists() || string(got.Bytes()) != string(v.Bytes()) {
+			t.Errorf("get %s: got %s", k, got.Bytes())
+		}
+	}
+}